@@ -2,50 +2,98 @@ package main
 
 import (
 	"errors"
+	"fmt"
+	"strings"
 
 	"github.com/openshift/cluster-bootstrap/pkg/bootstrapinplace"
 
 	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/sets"
 )
 
 var (
 	CmdBootstrapInPlace = &cobra.Command{
 		Use:          "bootstrap-in-place",
 		Short:        "Create Ignition based on Fedora CoreOS Config",
-		Long:         "",
+		Long:         "Create Ignition based on Fedora CoreOS Config.\n\nExits with 2 if the config or the generated Ignition config is invalid or exceeds --size-budget, with 3 if --diff found changes, and with 1 on any other error.",
 		PreRunE:      validateBootstrapInPlaceOpts,
 		RunE:         runCmdBootstrapInPlace,
 		SilenceUsage: true,
 	}
 
 	bootstrapInPlaceOpts struct {
-		assetDir     string
-		ignitionPath string
-		input        string
-		Pretty       bool
+		assetDir          string
+		ignitionPath      string
+		inputs            []string
+		includeRules      string
+		Pretty            bool
+		strict            bool
+		compressThreshold int
+		sizeBudget        int
+		reportSizes       bool
+		diff              bool
+		ignitionVersion   string
+		template          bool
+		clusterConfig     string
+		reportFormat      string
 	}
 )
 
 func init() {
 	cmdRoot.AddCommand(CmdBootstrapInPlace)
 	CmdBootstrapInPlace.Flags().BoolVarP(&bootstrapInPlaceOpts.Pretty, "pretty", "p", true, "output formatted json")
-	CmdBootstrapInPlace.Flags().StringVar(&bootstrapInPlaceOpts.input, "input", "", "fcc input file path")
+	CmdBootstrapInPlace.Flags().StringSliceVar(&bootstrapInPlaceOpts.inputs, "input", nil, "fcc input file paths, or directories of fcc fragments applied in lexical order; the ignition of several fragments is merged, later ones taking precedence")
+	CmdBootstrapInPlace.Flags().StringVar(&bootstrapInPlaceOpts.includeRules, "include-rules", "", "YAML file of rules selecting asset files to embed, {variant, version, rules: [{source, destination, mode, optional}]}; the generated fcc is applied before the --input fragments, and a rule matching no asset files is an error unless optional")
 	CmdBootstrapInPlace.Flags().StringVar(&bootstrapInPlaceOpts.ignitionPath, "output", "o", "Ignition output file path")
+	CmdBootstrapInPlace.Flags().BoolVar(&bootstrapInPlaceOpts.template, "template", false, "render the fcc inputs as Go templates before translating them, with the install config as .InstallConfig (typed) and .InstallConfigValues (generic), and the base64, indent and load (a file of the asset directory) functions")
+	CmdBootstrapInPlace.Flags().StringVar(&bootstrapInPlaceOpts.clusterConfig, "cluster-config", "", "cluster-config ConfigMap manifest the install config is read from with --template, defaults to manifests/cluster-config.yaml of the asset directory")
+	CmdBootstrapInPlace.Flags().StringVar(&bootstrapInPlaceOpts.ignitionVersion, "ignition-version", "", fmt.Sprintf("Ignition spec version of the output, one of %s; defaults to the version implied by the fcc variant and version", strings.Join(bootstrapinplace.SupportedIgnitionVersions(), ", ")))
+	CmdBootstrapInPlace.Flags().BoolVar(&bootstrapInPlaceOpts.diff, "diff", false, "print the changed files, units and users compared to the existing Ignition output file instead of writing it")
+	CmdBootstrapInPlace.Flags().IntVar(&bootstrapInPlaceOpts.compressThreshold, "compress-threshold", 0, "gzip-compress only embedded files larger than this many bytes, if it makes them smaller; by default, all files are compressed if it makes them smaller")
+	CmdBootstrapInPlace.Flags().IntVar(&bootstrapInPlaceOpts.sizeBudget, "size-budget", 0, "fail if the generated Ignition config is larger than this many bytes; 0 disables the check")
+	CmdBootstrapInPlace.Flags().BoolVar(&bootstrapInPlaceOpts.reportSizes, "report-sizes", false, "report the size each embedded file adds to the Ignition config; implied by --compress-threshold and --size-budget")
+	CmdBootstrapInPlace.Flags().BoolVar(&bootstrapInPlaceOpts.strict, "strict", false, "fail on any translation or validation warning")
+	CmdBootstrapInPlace.Flags().StringVar(&bootstrapInPlaceOpts.reportFormat, "report-format", string(bootstrapinplace.ReportText), "format of the translation report written to stdout, one of text or json")
 	CmdBootstrapInPlace.Flags().StringVarP(&bootstrapInPlaceOpts.assetDir, "asset-dir", "d", "", "allow embedding local files from this directory")
 }
 
 func runCmdBootstrapInPlace(cmd *cobra.Command, args []string) error {
 	bip, err := bootstrapinplace.NewBootstrapInPlaceCommand(bootstrapinplace.BootstrapInPlaceConfig{
-		AssetDir:     bootstrapInPlaceOpts.assetDir,
-		IgnitionPath: bootstrapInPlaceOpts.ignitionPath,
-		Input:        bootstrapInPlaceOpts.input,
-		Pretty:       bootstrapInPlaceOpts.Pretty,
+		AssetDir:          bootstrapInPlaceOpts.assetDir,
+		IgnitionPath:      bootstrapInPlaceOpts.ignitionPath,
+		Inputs:            bootstrapInPlaceOpts.inputs,
+		IncludeRules:      bootstrapInPlaceOpts.includeRules,
+		Pretty:            bootstrapInPlaceOpts.Pretty,
+		Template:          bootstrapInPlaceOpts.template,
+		ClusterConfig:     bootstrapInPlaceOpts.clusterConfig,
+		IgnitionVersion:   bootstrapInPlaceOpts.ignitionVersion,
+		Diff:              bootstrapInPlaceOpts.diff,
+		CompressThreshold: bootstrapInPlaceOpts.compressThreshold,
+		SizeBudget:        bootstrapInPlaceOpts.sizeBudget,
+		ReportSizes:       bootstrapInPlaceOpts.reportSizes,
+		Strict:            bootstrapInPlaceOpts.strict,
+		ReportFormat:      bootstrapinplace.ReportFormat(bootstrapInPlaceOpts.reportFormat),
 	})
 	if err != nil {
 		return err
 	}
 
-	return bip.Create()
+	err = bip.Create()
+	var (
+		templateErr   *bootstrapinplace.TemplateError
+		translateErr  *bootstrapinplace.TranslateError
+		mergeErr      *bootstrapinplace.MergeError
+		convertErr    *bootstrapinplace.ConvertError
+		validationErr *bootstrapinplace.ValidationError
+		sizeErr       *bootstrapinplace.SizeError
+	)
+	if errors.As(err, &templateErr) || errors.As(err, &translateErr) || errors.As(err, &mergeErr) || errors.As(err, &convertErr) || errors.As(err, &validationErr) || errors.As(err, &sizeErr) {
+		return &exitError{code: 2, err: err}
+	}
+	if errors.Is(err, bootstrapinplace.ErrChanged) {
+		return &exitError{code: 3, err: err}
+	}
+	return err
 }
 
 func validateBootstrapInPlaceOpts(cmd *cobra.Command, args []string) error {
@@ -55,8 +103,25 @@ func validateBootstrapInPlaceOpts(cmd *cobra.Command, args []string) error {
 	if bootstrapInPlaceOpts.assetDir == "" {
 		return errors.New("missing required flag: --asset-dir")
 	}
-	if bootstrapInPlaceOpts.input == "" {
-		return errors.New("missing required flag: --input")
+	if len(bootstrapInPlaceOpts.inputs) == 0 && bootstrapInPlaceOpts.includeRules == "" {
+		return errors.New("missing required flag: --input or --include-rules")
+	}
+	if bootstrapInPlaceOpts.clusterConfig != "" && !bootstrapInPlaceOpts.template {
+		return errors.New("--cluster-config requires --template")
+	}
+	if v := bootstrapInPlaceOpts.ignitionVersion; v != "" && !sets.NewString(bootstrapinplace.SupportedIgnitionVersions()...).Has(v) {
+		return fmt.Errorf("invalid --ignition-version %q, must be one of %s", v, strings.Join(bootstrapinplace.SupportedIgnitionVersions(), ", "))
+	}
+	if bootstrapInPlaceOpts.compressThreshold < 0 {
+		return errors.New("--compress-threshold must not be negative")
+	}
+	if bootstrapInPlaceOpts.sizeBudget < 0 {
+		return errors.New("--size-budget must not be negative")
+	}
+	switch bootstrapinplace.ReportFormat(bootstrapInPlaceOpts.reportFormat) {
+	case bootstrapinplace.ReportText, bootstrapinplace.ReportJSON:
+	default:
+		return fmt.Errorf("invalid --report-format %q, must be one of text or json", bootstrapInPlaceOpts.reportFormat)
 	}
 	return nil
 }
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/openshift/cluster-bootstrap/pkg/start"
+)
+
+var (
+	cmdCreateAssets = &cobra.Command{
+		Use:          "create-assets",
+		Short:        "Create the manifests of a directory in a cluster",
+		Long:         "Create the manifests of a directory in any cluster with the same engine as the start command, retrying until all are created or the timeout is hit, e.g. to create the remaining assets after a failed bootstrap, or for control planes without a bootstrap phase.\n\nExits with 2 if not all manifests were created, and with 1 on any other error.",
+		PreRunE:      validateCreateAssetsOpts,
+		RunE:         runCmdCreateAssets,
+		SilenceUsage: true,
+	}
+
+	createAssetsOpts struct {
+		kubeconfig           string
+		manifestsDir         string
+		manifestOverrideDirs []string
+		includeManifests     []string
+		excludeManifests     []string
+		clusterConfig        string
+		strict               bool
+		timeout              time.Duration
+		reportFile           string
+	}
+)
+
+func init() {
+	cmdRoot.AddCommand(cmdCreateAssets)
+	cmdCreateAssets.Flags().StringVar(&createAssetsOpts.kubeconfig, "kubeconfig", "", "Path to the kubeconfig of the cluster.")
+	cmdCreateAssets.Flags().StringVar(&createAssetsOpts.manifestsDir, "manifests-dir", "", "Path to the directory of the manifests to create.")
+	cmdCreateAssets.Flags().StringSliceVar(&createAssetsOpts.manifestOverrideDirs, "manifest-override-dirs", nil, "List of additional manifest directories layered on top of --manifests-dir, written as for the start command.")
+	cmdCreateAssets.Flags().StringSliceVar(&createAssetsOpts.includeManifests, "include-manifests", nil, "List of manifest selectors, written as for the start command. If given, only manifests matching any of them are created.")
+	cmdCreateAssets.Flags().StringSliceVar(&createAssetsOpts.excludeManifests, "exclude-manifests", nil, "List of manifest selectors like for --include-manifests. Manifests matching any of them are not created.")
+	cmdCreateAssets.Flags().StringVar(&createAssetsOpts.clusterConfig, "cluster-config", "", "Optional path to the cluster-config ConfigMap manifest with the install config. If given, manifests of other feature sets and of disabled capabilities are skipped.")
+	cmdCreateAssets.Flags().BoolVar(&createAssetsOpts.strict, "strict", false, "Stop retrying as soon as manifests fail to be created for other reasons than kinds which are unknown yet.")
+	cmdCreateAssets.Flags().DurationVar(&createAssetsOpts.timeout, "timeout", 60*time.Minute, "How long to wait for all manifests to be created.")
+	cmdCreateAssets.Flags().StringVar(&createAssetsOpts.reportFile, "report-file", "", "Optional path to write the asset creation report to.")
+}
+
+func runCmdCreateAssets(cmd *cobra.Command, args []string) error {
+	manifestSelection, err := parseManifestSelection(createAssetsOpts.includeManifests, createAssetsOpts.excludeManifests)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), createAssetsOpts.timeout)
+	defer cancel()
+	err = start.CreateAssets(ctx, start.CreateAssetsConfig{
+		Kubeconfig:           createAssetsOpts.kubeconfig,
+		ManifestDir:          createAssetsOpts.manifestsDir,
+		ManifestOverrideDirs: createAssetsOpts.manifestOverrideDirs,
+		ManifestSelection:    manifestSelection,
+		ClusterConfig:        createAssetsOpts.clusterConfig,
+		Strict:               createAssetsOpts.strict,
+		ReportFile:           createAssetsOpts.reportFile,
+	})
+	if errors.Is(err, start.ErrAssetsNotCreated) {
+		return &exitError{code: 2, err: err}
+	}
+	return err
+}
+
+func validateCreateAssetsOpts(cmd *cobra.Command, args []string) error {
+	if createAssetsOpts.kubeconfig == "" {
+		return errors.New("missing required flag: --kubeconfig")
+	}
+	if createAssetsOpts.manifestsDir == "" {
+		return errors.New("missing required flag: --manifests-dir")
+	}
+	if info, err := os.Stat(createAssetsOpts.manifestsDir); err != nil {
+		return fmt.Errorf("invalid manifests directory: %w", err)
+	} else if !info.IsDir() {
+		return fmt.Errorf("invalid manifests directory %s: not a directory", createAssetsOpts.manifestsDir)
+	}
+	if _, err := parseManifestSelection(createAssetsOpts.includeManifests, createAssetsOpts.excludeManifests); err != nil {
+		return err
+	}
+	return validateManifestOverrideDirs(createAssetsOpts.manifestOverrideDirs)
+}
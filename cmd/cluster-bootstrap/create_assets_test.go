@@ -0,0 +1,42 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_validateCreateAssetsOpts(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name         string
+		manifestsDir string
+		wantErr      string
+	}{
+		{"valid", dir, ""},
+		{"missing", filepath.Join(dir, "missing"), "invalid manifests directory"},
+		{"file", file, "not a directory"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			saved := createAssetsOpts
+			defer func() { createAssetsOpts = saved }()
+			createAssetsOpts.kubeconfig = "kubeconfig"
+			createAssetsOpts.manifestsDir = tt.manifestsDir
+
+			err := validateCreateAssetsOpts(cmdCreateAssets, nil)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validateCreateAssetsOpts() unexpected error: %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateCreateAssetsOpts() error = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/openshift/cluster-bootstrap/pkg/start"
+)
+
+var (
+	cmdGather = &cobra.Command{
+		Use:          "gather",
+		Short:        "Gather a diagnostics archive of the bootstrap",
+		Long:         "Gather a gzip compressed tar archive with the asset dir layout, the ownership of the copied static pod manifests, the statuses of the required pods, the events of kube-system and the openshift-* namespaces, the operator NodeStatuses, the asset creation report and the rendered configs. Private keys, credentials and the contents of the TLS assets are redacted.",
+		PreRunE:      validateGatherOpts,
+		RunE:         runCmdGather,
+		SilenceUsage: true,
+	}
+
+	gatherOpts struct {
+		assetDir            string
+		podManifestPath     string
+		kubeconfig          string
+		external            bool
+		requiredPodClauses  []string
+		assetCreationReport string
+		configFiles         []string
+		output              string
+		timeout             time.Duration
+	}
+)
+
+func init() {
+	cmdRoot.AddCommand(cmdGather)
+	cmdGather.Flags().StringVar(&gatherOpts.assetDir, "asset-dir", "", "Path to the cluster asset directory.")
+	cmdGather.Flags().StringVar(&gatherOpts.podManifestPath, "pod-manifest-path", "/etc/kubernetes/manifests", "The location where the kubelet is configured to look for static pod manifests.")
+	cmdGather.Flags().StringVar(&gatherOpts.kubeconfig, "kubeconfig", "", "Path to the kubeconfig to gather from the cluster with. Defaults to the loopback kubeconfig of the asset directory.")
+	cmdGather.Flags().BoolVar(&gatherOpts.external, "external", false, "Gather from the server of the kubeconfig instead of the local apiserver on localhost:6443.")
+	cmdGather.Flags().StringSliceVar(&gatherOpts.requiredPodClauses, "required-pods", defaultRequiredPods, "List of required pods whose statuses are gathered, written as for the start command.")
+	cmdGather.Flags().StringVar(&gatherOpts.assetCreationReport, "asset-creation-report", "", "Path to the asset creation report. Defaults to the one of the asset directory.")
+	cmdGather.Flags().StringSliceVar(&gatherOpts.configFiles, "config-files", nil, "List of additional rendered config files to gather, e.g. Ignition configs.")
+	cmdGather.Flags().StringVarP(&gatherOpts.output, "output", "o", "bootstrap-gather.tar.gz", "Path of the archive to write.")
+	cmdGather.Flags().DurationVar(&gatherOpts.timeout, "timeout", 2*time.Minute, "How long to wait for the cluster to be gathered from.")
+}
+
+func runCmdGather(cmd *cobra.Command, args []string) error {
+	podPrefixes, err := parsePodPrefixes(gatherOpts.requiredPodClauses)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), gatherOpts.timeout)
+	defer cancel()
+	return start.Gather(ctx, start.GatherConfig{
+		AssetDir:            gatherOpts.assetDir,
+		PodManifestPath:     gatherOpts.podManifestPath,
+		Kubeconfig:          gatherOpts.kubeconfig,
+		External:            gatherOpts.external,
+		RequiredPodPrefixes: podPrefixes,
+		AssetCreationReport: gatherOpts.assetCreationReport,
+		ConfigFiles:         gatherOpts.configFiles,
+		Output:              gatherOpts.output,
+	})
+}
+
+func validateGatherOpts(cmd *cobra.Command, args []string) error {
+	if gatherOpts.assetDir == "" {
+		return errors.New("missing required flag: --asset-dir")
+	}
+	if gatherOpts.output == "" {
+		return errors.New("missing required flag: --output")
+	}
+	if _, err := parsePodPrefixes(gatherOpts.requiredPodClauses); err != nil {
+		return err
+	}
+	return nil
+}
@@ -1,13 +1,12 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"os"
 
 	"github.com/spf13/cobra"
-
-	"github.com/openshift/cluster-bootstrap/pkg/version"
 )
 
 var (
@@ -17,26 +16,33 @@ var (
 		SilenceErrors: true, // suppress cobra errors so we can handle them (also applies to subcommands)
 		Long:          "",
 	}
-
-	cmdVersion = &cobra.Command{
-		Use:   "version",
-		Short: "Output version information",
-		Long:  "",
-		RunE: func(cmd *cobra.Command, args []string) error {
-			fmt.Printf("Version: %s\n", version.Version)
-			return nil
-		},
-	}
 )
 
+// exitError makes the command exit with the given code instead of 1.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
 func main() {
 	flag.Parse()
 	InitLogs()
 	defer FlushLogs()
 
-	cmdRoot.AddCommand(cmdVersion)
 	if err := cmdRoot.Execute(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		var exitErr *exitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.code)
+		}
 		os.Exit(1)
 	}
 }
@@ -2,11 +2,15 @@ package main
 
 import (
 	"errors"
+	"fmt"
+	"os"
 	"strings"
 	"time"
 
+	configv1 "github.com/openshift/api/config/v1"
 	"github.com/spf13/cobra"
 
+	"github.com/openshift/cluster-bootstrap/pkg/create"
 	"github.com/openshift/cluster-bootstrap/pkg/start"
 )
 
@@ -21,15 +25,26 @@ var (
 	}
 
 	startOpts struct {
-		assetDir             string
-		podManifestPath      string
-		strict               bool
-		requiredPodClauses   []string
-		waitForTearDownEvent string
-		earlyTearDown        bool
-		terminationTimeout   time.Duration
-		tearDownDelay        time.Duration
-		assetsCreatedTimeout time.Duration
+		assetDir                       string
+		assetArchive                   string
+		podManifestPath                string
+		strict                         bool
+		requiredPodClauses             []string
+		waitForTearDownEvent           string
+		earlyTearDown                  bool
+		terminationTimeout             time.Duration
+		tearDownDelay                  time.Duration
+		assetsCreatedTimeout           time.Duration
+		requiredClusterOperatorClauses []string
+		includeManifests               []string
+		excludeManifests               []string
+		manifestOverrideDirs           []string
+		assetChecksums                 string
+		assetChecksumsSignature        string
+		assetChecksumsPublicKey        string
+		hooksDir                       string
+		hookTimeout                    time.Duration
+		hookFailurePolicy              string
 	}
 )
 
@@ -43,6 +58,7 @@ var defaultRequiredPods = []string{
 func init() {
 	cmdRoot.AddCommand(cmdStart)
 	cmdStart.Flags().StringVar(&startOpts.assetDir, "asset-dir", "", "Path to the cluster asset directory.")
+	cmdStart.Flags().StringVar(&startOpts.assetArchive, "asset-archive", "", "Path to a tar archive, optionally gzip compressed, of the cluster asset directory, as an alternative to --asset-dir. It is unpacked into a private staging directory which is removed on exit.")
 	cmdStart.Flags().StringVar(&startOpts.podManifestPath, "pod-manifest-path", "/etc/kubernetes/manifests", "The location where the kubelet is configured to look for static pod manifests.")
 	cmdStart.Flags().BoolVar(&startOpts.strict, "strict", false, "Strict mode will cause start command to exit early if any manifests in the asset directory cannot be created.")
 	cmdStart.Flags().StringSliceVar(&startOpts.requiredPodClauses, "required-pods", defaultRequiredPods, "List of pods name prefixes with their namespace (written as <namespace>/<pod-prefix>) that are required to be running and ready before the start command does the pivot, or alternatively a list of or'ed pod prefixes with a description (written as <desc>:<namespace>/<pod-prefix>|<namespace>/<pod-prefix>|...).")
@@ -51,6 +67,16 @@ func init() {
 	cmdStart.Flags().DurationVar(&startOpts.terminationTimeout, "tear-down-termination-timeout", 0, "wait of (graceful) termination of the bootstrap control-plane before reporting success. Set to zero to disable.")
 	cmdStart.Flags().DurationVar(&startOpts.tearDownDelay, "tear-down-delay", 0, "duration to delay the bootstrap control-plane tear-down before bootstrap-success event is created, in order to give load-balancers time to observe the self-hosted control-plane. This even applies in case of --tear-down-early.")
 	cmdStart.Flags().DurationVar(&startOpts.assetsCreatedTimeout, "assets-create-timeout", time.Duration(60)*time.Minute, "how long to wait for all the assets be created.")
+	cmdStart.Flags().StringVar(&startOpts.assetChecksums, "asset-checksums", "", "Optional path to a checksum manifest in sha256sum format, with paths relative to the asset directory. If given, every file in the tls, auth, manifests and bootstrap-manifests asset directories must match it before the control plane is started. Cannot be used with --manifest-override-dirs.")
+	cmdStart.Flags().StringVar(&startOpts.assetChecksumsSignature, "asset-checksums-signature", "", "Path to the detached signature, raw or base64 encoded, of the --asset-checksums manifest.")
+	cmdStart.Flags().StringVar(&startOpts.assetChecksumsPublicKey, "asset-checksums-public-key", "", "Path to the PEM encoded RSA, ECDSA or Ed25519 public key to verify --asset-checksums-signature with. If given, the signature is required.")
+	cmdStart.Flags().StringSliceVar(&startOpts.includeManifests, "include-manifests", nil, "List of manifest selectors written as path=<glob>, gvk=<glob> or namespace=<glob>. If given, only manifests matching any of them are created. Paths are relative to the manifests directory, GVKs are written as <group>/<version>/<kind> or <version>/<kind> for the core group.")
+	cmdStart.Flags().StringSliceVar(&startOpts.excludeManifests, "exclude-manifests", nil, "List of manifest selectors like for --include-manifests. Manifests matching any of them are not created, even if they match --include-manifests.")
+	cmdStart.Flags().StringSliceVar(&startOpts.manifestOverrideDirs, "manifest-override-dirs", nil, "List of additional manifest directories layered on top of the manifests of the asset directory, in increasing order of precedence. If several layers define the same object, i.e. the same group, kind, namespace and name, only the manifest of the highest layer is created. Cannot be used with --asset-checksums.")
+	cmdStart.Flags().StringVar(&startOpts.hooksDir, "hooks-dir", "", "Optional directory with a subdirectory of executables per phase, one of "+hookPhaseNames()+", which are run in lexical order at that phase. Hooks get the phase, the asset directory and the loopback kubeconfig in the BOOTSTRAP_HOOK_PHASE, BOOTSTRAP_ASSET_DIR and KUBECONFIG environment variables.")
+	cmdStart.Flags().DurationVar(&startOpts.hookTimeout, "hook-timeout", 5*time.Minute, "How long a single hook may run. Set to zero to disable.")
+	cmdStart.Flags().StringVar(&startOpts.hookFailurePolicy, "hook-failure-policy", string(start.HookFailurePolicyFail), "What to do if a hook fails or times out, either Fail to fail the start command or Ignore to continue.")
+	cmdStart.Flags().StringSliceVar(&startOpts.requiredClusterOperatorClauses, "required-cluster-operators", nil, "List of cluster operators that are required to report the given conditions before the bootstrap-success event is sent, written as <name> (for Available=True and Degraded=False) or <name>:<condition>=<status>|<condition>=<status>|... .")
 }
 
 func runCmdStart(cmd *cobra.Command, args []string) error {
@@ -59,16 +85,36 @@ func runCmdStart(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	clusterOperators, err := parseClusterOperatorRequirements(startOpts.requiredClusterOperatorClauses)
+	if err != nil {
+		return err
+	}
+
+	manifestSelection, err := parseManifestSelection(startOpts.includeManifests, startOpts.excludeManifests)
+	if err != nil {
+		return err
+	}
+
 	bk, err := start.NewStartCommand(start.Config{
-		AssetDir:             startOpts.assetDir,
-		PodManifestPath:      startOpts.podManifestPath,
-		Strict:               startOpts.strict,
-		RequiredPodPrefixes:  podPrefixes,
-		WaitForTearDownEvent: startOpts.waitForTearDownEvent,
-		EarlyTearDown:        startOpts.earlyTearDown,
-		TerminationTimeout:   startOpts.terminationTimeout,
-		TearDownDelay:        startOpts.tearDownDelay,
-		AssetsCreatedTimeout: startOpts.assetsCreatedTimeout,
+		AssetDir:                 startOpts.assetDir,
+		AssetArchive:             startOpts.assetArchive,
+		PodManifestPath:          startOpts.podManifestPath,
+		Strict:                   startOpts.strict,
+		RequiredPodPrefixes:      podPrefixes,
+		WaitForTearDownEvent:     startOpts.waitForTearDownEvent,
+		EarlyTearDown:            startOpts.earlyTearDown,
+		TerminationTimeout:       startOpts.terminationTimeout,
+		TearDownDelay:            startOpts.tearDownDelay,
+		AssetsCreatedTimeout:     startOpts.assetsCreatedTimeout,
+		RequiredClusterOperators: clusterOperators,
+		ManifestSelection:        manifestSelection,
+		ManifestOverrideDirs:     startOpts.manifestOverrideDirs,
+		AssetChecksums:           startOpts.assetChecksums,
+		AssetChecksumsSignature:  startOpts.assetChecksumsSignature,
+		AssetChecksumsPublicKey:  startOpts.assetChecksumsPublicKey,
+		HooksDir:                 startOpts.hooksDir,
+		HookTimeout:              startOpts.hookTimeout,
+		HookFailurePolicy:        start.HookFailurePolicy(startOpts.hookFailurePolicy),
 	})
 	if err != nil {
 		return err
@@ -96,15 +142,129 @@ func parsePodPrefixes(clauses []string) (map[string][]string, error) {
 	return podPrefixes, nil
 }
 
+// parseClusterOperatorRequirements parses <name> or <name>:<condition>=<status>|... into
+// cluster operator requirements. A plain <name> requires the default conditions.
+func parseClusterOperatorRequirements(clauses []string) ([]start.ClusterOperatorRequirement, error) {
+	requirements := []start.ClusterOperatorRequirement{}
+	for _, c := range clauses {
+		ss := strings.SplitN(c, ":", 2)
+		name := ss[0]
+		if len(name) == 0 {
+			return nil, fmt.Errorf("required-cluster-operators %q is missing the cluster operator name", c)
+		}
+		if len(ss) == 1 {
+			requirements = append(requirements, start.ClusterOperatorRequirement{Name: name, Conditions: start.DefaultClusterOperatorConditions()})
+			continue
+		}
+		conditions := map[configv1.ClusterStatusConditionType]configv1.ConditionStatus{}
+		for _, cond := range strings.Split(ss[1], "|") {
+			kv := strings.Split(cond, "=")
+			if len(kv) != 2 || len(kv[0]) == 0 {
+				return nil, fmt.Errorf("required-cluster-operators must be either <name> or <name>:<condition>=<status>|<condition>=<status>|..., got %q", c)
+			}
+			status := configv1.ConditionStatus(kv[1])
+			switch status {
+			case configv1.ConditionTrue, configv1.ConditionFalse, configv1.ConditionUnknown:
+			default:
+				return nil, fmt.Errorf("required-cluster-operators condition status must be one of True, False or Unknown, got %q", c)
+			}
+			conditions[configv1.ClusterStatusConditionType(kv[0])] = status
+		}
+		requirements = append(requirements, start.ClusterOperatorRequirement{Name: name, Conditions: conditions})
+	}
+	return requirements, nil
+}
+
+// parseManifestSelection parses the include and exclude selectors of the form <field>=<glob>.
+func parseManifestSelection(include, exclude []string) (create.Selection, error) {
+	selection := create.Selection{}
+	for _, s := range include {
+		sel, err := create.ParseSelector(s)
+		if err != nil {
+			return create.Selection{}, err
+		}
+		selection.Include = append(selection.Include, sel)
+	}
+	for _, s := range exclude {
+		sel, err := create.ParseSelector(s)
+		if err != nil {
+			return create.Selection{}, err
+		}
+		selection.Exclude = append(selection.Exclude, sel)
+	}
+	return selection, nil
+}
+
+// hookPhaseNames returns the names of the hook phases in the order they are run.
+func hookPhaseNames() string {
+	names := make([]string, 0, len(start.HookPhases))
+	for _, phase := range start.HookPhases {
+		names = append(names, string(phase))
+	}
+	return strings.Join(names, ", ")
+}
+
+// validateManifestOverrideDirs checks that the manifest override directories exist.
+func validateManifestOverrideDirs(dirs []string) error {
+	for _, dir := range dirs {
+		if info, err := os.Stat(dir); err != nil {
+			return fmt.Errorf("invalid manifest override directory: %w", err)
+		} else if !info.IsDir() {
+			return fmt.Errorf("invalid manifest override directory %s: not a directory", dir)
+		}
+	}
+	return nil
+}
+
 func validateStartOpts(cmd *cobra.Command, args []string) error {
 	if startOpts.podManifestPath == "" {
 		return errors.New("missing required flag: --pod-manifest-path")
 	}
-	if startOpts.assetDir == "" {
-		return errors.New("missing required flag: --asset-dir")
+	if startOpts.assetDir == "" && startOpts.assetArchive == "" {
+		return errors.New("missing required flag: --asset-dir or --asset-archive")
+	}
+	if startOpts.assetDir != "" && startOpts.assetArchive != "" {
+		return errors.New("--asset-dir and --asset-archive are mutually exclusive")
 	}
 	if _, err := parsePodPrefixes(startOpts.requiredPodClauses); err != nil {
 		return err
 	}
+	if _, err := parseClusterOperatorRequirements(startOpts.requiredClusterOperatorClauses); err != nil {
+		return err
+	}
+	if _, err := parseManifestSelection(startOpts.includeManifests, startOpts.excludeManifests); err != nil {
+		return err
+	}
+	if err := validateManifestOverrideDirs(startOpts.manifestOverrideDirs); err != nil {
+		return err
+	}
+	if startOpts.assetChecksums != "" && len(startOpts.manifestOverrideDirs) > 0 {
+		// the checksums only cover the asset directory, not the manifests overriding it
+		return errors.New("--asset-checksums and --manifest-override-dirs are mutually exclusive")
+	}
+	if startOpts.assetChecksums == "" && (startOpts.assetChecksumsSignature != "" || startOpts.assetChecksumsPublicKey != "") {
+		return errors.New("--asset-checksums-signature and --asset-checksums-public-key require --asset-checksums")
+	}
+	if startOpts.assetChecksumsPublicKey == "" && startOpts.assetChecksumsSignature != "" {
+		return errors.New("--asset-checksums-signature requires --asset-checksums-public-key")
+	}
+	if startOpts.assetChecksumsPublicKey != "" && startOpts.assetChecksumsSignature == "" {
+		return errors.New("--asset-checksums-public-key requires --asset-checksums-signature")
+	}
+	if startOpts.hooksDir != "" {
+		if info, err := os.Stat(startOpts.hooksDir); err != nil {
+			return fmt.Errorf("invalid hooks directory: %w", err)
+		} else if !info.IsDir() {
+			return fmt.Errorf("invalid hooks directory %s: not a directory", startOpts.hooksDir)
+		}
+	}
+	switch start.HookFailurePolicy(startOpts.hookFailurePolicy) {
+	case start.HookFailurePolicyFail, start.HookFailurePolicyIgnore:
+	default:
+		return fmt.Errorf("invalid --hook-failure-policy %q, must be one of Fail or Ignore", startOpts.hookFailurePolicy)
+	}
+	if startOpts.hookTimeout < 0 {
+		return errors.New("--hook-timeout must not be negative")
+	}
 	return nil
 }
@@ -3,6 +3,10 @@ package main
 import (
 	"reflect"
 	"testing"
+
+	configv1 "github.com/openshift/api/config/v1"
+
+	"github.com/openshift/cluster-bootstrap/pkg/start"
 )
 
 func Test_parsePodPrefixes(t *testing.T) {
@@ -36,3 +40,33 @@ func Test_parsePodPrefixes(t *testing.T) {
 		})
 	}
 }
+
+func Test_parseClusterOperatorRequirements(t *testing.T) {
+	tests := []struct {
+		name     string
+		clauses  []string
+		expected []start.ClusterOperatorRequirement
+		wantErr  bool
+	}{
+		{"empty", []string{}, []start.ClusterOperatorRequirement{}, false},
+		{"nil", nil, []start.ClusterOperatorRequirement{}, false},
+		{"default-conditions", []string{"etcd"}, []start.ClusterOperatorRequirement{{Name: "etcd", Conditions: start.DefaultClusterOperatorConditions()}}, false},
+		{"conditions", []string{"etcd:Available=True|Progressing=False"}, []start.ClusterOperatorRequirement{{Name: "etcd", Conditions: map[configv1.ClusterStatusConditionType]configv1.ConditionStatus{"Available": "True", "Progressing": "False"}}}, false},
+		{"mixed", []string{"etcd", "kube-apiserver:Degraded=False"}, []start.ClusterOperatorRequirement{{Name: "etcd", Conditions: start.DefaultClusterOperatorConditions()}, {Name: "kube-apiserver", Conditions: map[configv1.ClusterStatusConditionType]configv1.ConditionStatus{"Degraded": "False"}}}, false},
+		{"no-name", []string{":Available=True"}, nil, true},
+		{"no-status", []string{"etcd:Available"}, nil, true},
+		{"invalid-status", []string{"etcd:Available=Yes"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseClusterOperatorRequirements(tt.clauses)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseClusterOperatorRequirements() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("parseClusterOperatorRequirements() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/openshift/cluster-bootstrap/pkg/start"
+)
+
+var (
+	cmdStatus = &cobra.Command{
+		Use:          "status",
+		Short:        "Report the bootstrap progress of a cluster",
+		Long:         "Report the bootstrap progress of a cluster: the bootstrap events, the required pods and the conditions the start command waits for before sending the bootstrap-success event.\n\nExits with 0 if bootstrap has finished, with 2 if it is still in progress, and with 1 if the status cannot be queried.",
+		PreRunE:      validateStatusOpts,
+		RunE:         runCmdStatus,
+		SilenceUsage: true,
+	}
+
+	statusOpts struct {
+		kubeconfig                     string
+		requiredPodClauses             []string
+		requiredClusterOperatorClauses []string
+		controlPlaneReplicas           int
+		output                         string
+		timeout                        time.Duration
+	}
+)
+
+func init() {
+	cmdRoot.AddCommand(cmdStatus)
+	cmdStatus.Flags().StringVar(&statusOpts.kubeconfig, "kubeconfig", "", "Path to the kubeconfig of the cluster.")
+	cmdStatus.Flags().StringSliceVar(&statusOpts.requiredPodClauses, "required-pods", defaultRequiredPods, "List of required pods, written as for the start command.")
+	cmdStatus.Flags().StringSliceVar(&statusOpts.requiredClusterOperatorClauses, "required-cluster-operators", nil, "List of required cluster operators, written as for the start command.")
+	cmdStatus.Flags().IntVar(&statusOpts.controlPlaneReplicas, "control-plane-replicas", 0, "Number of control plane replicas, which decides whether the availability of an HA control plane is checked. By default, it is read from the install config of the cluster.")
+	cmdStatus.Flags().StringVarP(&statusOpts.output, "output", "o", "text", "Output format, one of text or json.")
+	cmdStatus.Flags().DurationVar(&statusOpts.timeout, "timeout", 30*time.Second, "How long to wait for the status to be queried.")
+}
+
+func runCmdStatus(cmd *cobra.Command, args []string) error {
+	podPrefixes, err := parsePodPrefixes(statusOpts.requiredPodClauses)
+	if err != nil {
+		return err
+	}
+	clusterOperators, err := parseClusterOperatorRequirements(statusOpts.requiredClusterOperatorClauses)
+	if err != nil {
+		return err
+	}
+	restConfig, err := clientcmd.BuildConfigFromFlags("", statusOpts.kubeconfig)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), statusOpts.timeout)
+	defer cancel()
+	status, err := start.QueryBootstrapStatus(ctx, restConfig, start.StatusConfig{
+		RequiredPodPrefixes:      podPrefixes,
+		ControlPlaneReplicas:     statusOpts.controlPlaneReplicas,
+		RequiredClusterOperators: clusterOperators,
+	})
+	if err != nil {
+		return err
+	}
+	if err := status.Write(os.Stdout, statusOpts.output == "json"); err != nil {
+		return err
+	}
+
+	if status.Phase != start.PhaseFinished {
+		return &exitError{code: 2, err: fmt.Errorf("bootstrap is in progress, phase %s", status.Phase)}
+	}
+	return nil
+}
+
+func validateStatusOpts(cmd *cobra.Command, args []string) error {
+	if statusOpts.kubeconfig == "" {
+		return errors.New("missing required flag: --kubeconfig")
+	}
+	if statusOpts.output != "text" && statusOpts.output != "json" {
+		return fmt.Errorf("invalid --output %q, must be one of text or json", statusOpts.output)
+	}
+	if statusOpts.controlPlaneReplicas < 0 {
+		return errors.New("--control-plane-replicas must not be negative")
+	}
+	if _, err := parsePodPrefixes(statusOpts.requiredPodClauses); err != nil {
+		return err
+	}
+	if _, err := parseClusterOperatorRequirements(statusOpts.requiredClusterOperatorClauses); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+
+	"github.com/openshift/cluster-bootstrap/pkg/version"
+)
+
+var (
+	cmdVersion = &cobra.Command{
+		Use:          "version",
+		Short:        "Output version information",
+		Long:         "Output version information. The json and yaml outputs add the git commit and tree state, the build date, the Go version and the versions of the key dependencies.",
+		PreRunE:      validateVersionOpts,
+		RunE:         runCmdVersion,
+		SilenceUsage: true,
+	}
+
+	versionOpts struct {
+		output string
+	}
+)
+
+func init() {
+	cmdRoot.AddCommand(cmdVersion)
+	cmdVersion.Flags().StringVarP(&versionOpts.output, "output", "o", "", "Output format, one of json or yaml. By default, only the version is printed.")
+}
+
+func runCmdVersion(cmd *cobra.Command, args []string) error {
+	switch versionOpts.output {
+	case "json":
+		data, err := json.MarshalIndent(version.Get(), "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s\n", data)
+	case "yaml":
+		data, err := yaml.Marshal(version.Get())
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s", data)
+	default:
+		fmt.Printf("Version: %s\n", version.Get().Version)
+	}
+	return nil
+}
+
+func validateVersionOpts(cmd *cobra.Command, args []string) error {
+	if versionOpts.output != "" && versionOpts.output != "json" && versionOpts.output != "yaml" {
+		return fmt.Errorf("invalid --output %q, must be one of json or yaml", versionOpts.output)
+	}
+	return nil
+}
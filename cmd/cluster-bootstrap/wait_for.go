@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/openshift/cluster-bootstrap/pkg/start"
+)
+
+var (
+	cmdWaitFor = &cobra.Command{
+		Use:   "wait-for",
+		Short: "Wait for one of the bootstrap gates of the start command",
+		Long:  "Wait for one of the bootstrap gates of the start command.\n\nExits with 2 if the gate is not reached within the timeout, and with 1 on any other error.",
+	}
+
+	cmdWaitForPods = &cobra.Command{
+		Use:          "pods",
+		Short:        "Wait until the required pods are running and ready",
+		PreRunE:      validateWaitForPodsOpts,
+		RunE:         runCmdWaitForPods,
+		SilenceUsage: true,
+	}
+
+	cmdWaitForControlPlane = &cobra.Command{
+		Use:          "control-plane",
+		Short:        "Wait until the self-hosted control plane is available on at least two master nodes and etcd has quorum",
+		PreRunE:      validateWaitForControlPlaneOpts,
+		RunE:         runCmdWaitForControlPlane,
+		SilenceUsage: true,
+	}
+
+	cmdWaitForEvent = &cobra.Command{
+		Use:          "event <namespace>/<event-name>",
+		Short:        "Wait until the event exists, e.g. kube-system/bootstrap-success",
+		Args:         cobra.ExactArgs(1),
+		PreRunE:      validateWaitForEventOpts,
+		RunE:         runCmdWaitForEvent,
+		SilenceUsage: true,
+	}
+
+	waitForOpts struct {
+		kubeconfig           string
+		requiredPodClauses   []string
+		controlPlaneReplicas int
+		podsTimeout          time.Duration
+		controlPlaneTimeout  time.Duration
+		eventTimeout         time.Duration
+	}
+)
+
+func init() {
+	cmdRoot.AddCommand(cmdWaitFor)
+	cmdWaitFor.AddCommand(cmdWaitForPods, cmdWaitForControlPlane, cmdWaitForEvent)
+	cmdWaitFor.PersistentFlags().StringVar(&waitForOpts.kubeconfig, "kubeconfig", "", "Path to the kubeconfig of the cluster.")
+	cmdWaitForPods.Flags().StringSliceVar(&waitForOpts.requiredPodClauses, "required-pods", defaultRequiredPods, "List of required pods, written as for the start command.")
+	cmdWaitForPods.Flags().DurationVar(&waitForOpts.podsTimeout, "timeout", 20*time.Minute, "How long to wait for the pods.")
+	cmdWaitForControlPlane.Flags().IntVar(&waitForOpts.controlPlaneReplicas, "control-plane-replicas", 0, "Number of control plane replicas. The control plane is only waited for if it has at least three. By default, it is read from the install config of the cluster.")
+	cmdWaitForControlPlane.Flags().DurationVar(&waitForOpts.controlPlaneTimeout, "timeout", 30*time.Minute, "How long to wait for the control plane.")
+	cmdWaitForEvent.Flags().DurationVar(&waitForOpts.eventTimeout, "timeout", 60*time.Minute, "How long to wait for the event.")
+}
+
+func runCmdWaitForPods(cmd *cobra.Command, args []string) error {
+	podPrefixes, err := parsePodPrefixes(waitForOpts.requiredPodClauses)
+	if err != nil {
+		return err
+	}
+	restConfig, err := clientcmd.BuildConfigFromFlags("", waitForOpts.kubeconfig)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), waitForOpts.podsTimeout)
+	defer cancel()
+	return waitForExitError(start.WaitForPods(ctx, restConfig, podPrefixes))
+}
+
+func runCmdWaitForControlPlane(cmd *cobra.Command, args []string) error {
+	restConfig, err := clientcmd.BuildConfigFromFlags("", waitForOpts.kubeconfig)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), waitForOpts.controlPlaneTimeout)
+	defer cancel()
+	return waitForExitError(start.WaitForControlPlane(ctx, restConfig, waitForOpts.controlPlaneReplicas, waitForOpts.controlPlaneTimeout))
+}
+
+func runCmdWaitForEvent(cmd *cobra.Command, args []string) error {
+	ns, name, err := parseEventName(args[0])
+	if err != nil {
+		return err
+	}
+	restConfig, err := clientcmd.BuildConfigFromFlags("", waitForOpts.kubeconfig)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), waitForOpts.eventTimeout)
+	defer cancel()
+	return waitForExitError(start.WaitForEvent(ctx, restConfig, ns, name))
+}
+
+// waitForExitError makes the command exit with 2 if the gate was not reached in time.
+func waitForExitError(err error) error {
+	if err != nil && start.IsTimeout(err) {
+		return &exitError{code: 2, err: err}
+	}
+	return err
+}
+
+// parseEventName parses <namespace>/<event-name>.
+func parseEventName(s string) (string, string, error) {
+	ss := strings.Split(s, "/")
+	if len(ss) != 2 || len(ss[0]) == 0 || len(ss[1]) == 0 {
+		return "", "", fmt.Errorf("event name of format <namespace>/<event-name> expected, got: %q", s)
+	}
+	return ss[0], ss[1], nil
+}
+
+func validateWaitForKubeconfig() error {
+	if waitForOpts.kubeconfig == "" {
+		return errors.New("missing required flag: --kubeconfig")
+	}
+	return nil
+}
+
+func validateWaitForPodsOpts(cmd *cobra.Command, args []string) error {
+	if err := validateWaitForKubeconfig(); err != nil {
+		return err
+	}
+	_, err := parsePodPrefixes(waitForOpts.requiredPodClauses)
+	return err
+}
+
+func validateWaitForControlPlaneOpts(cmd *cobra.Command, args []string) error {
+	if err := validateWaitForKubeconfig(); err != nil {
+		return err
+	}
+	if waitForOpts.controlPlaneReplicas < 0 {
+		return errors.New("--control-plane-replicas must not be negative")
+	}
+	return nil
+}
+
+func validateWaitForEventOpts(cmd *cobra.Command, args []string) error {
+	if err := validateWaitForKubeconfig(); err != nil {
+		return err
+	}
+	_, _, err := parseEventName(args[0])
+	return err
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"testing"
+)
+
+func Test_parseEventName(t *testing.T) {
+	tests := []struct {
+		name    string
+		event   string
+		ns      string
+		evName  string
+		wantErr bool
+	}{
+		{"valid", "kube-system/bootstrap-success", "kube-system", "bootstrap-success", false},
+		{"no-namespace", "bootstrap-success", "", "", true},
+		{"empty-namespace", "/bootstrap-success", "", "", true},
+		{"empty-name", "kube-system/", "", "", true},
+		{"too-many-parts", "kube-system/foo/bar", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ns, name, err := parseEventName(tt.event)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseEventName() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if ns != tt.ns || name != tt.evName {
+				t.Errorf("parseEventName() = %s, %s, want %s, %s", ns, name, tt.ns, tt.evName)
+			}
+		})
+	}
+}
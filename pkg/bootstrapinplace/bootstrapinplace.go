@@ -3,30 +3,159 @@
 package bootstrapinplace
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 
 	"github.com/coreos/butane/config"
 	butaneCommon "github.com/coreos/butane/config/common"
+	ignition "github.com/coreos/ignition/v2/config"
+
+	"github.com/openshift/cluster-bootstrap/pkg/installconfig"
+	"github.com/openshift/cluster-bootstrap/pkg/redact"
 )
 
-func fail(format string, args ...interface{}) {
-	fmt.Printf(format, args...)
-	os.Exit(1)
+// ErrStrict is wrapped by TranslateError, MergeError and ValidationError if the report
+// contains warnings in strict mode.
+var ErrStrict = errors.New("config produced warnings and strict mode is enabled")
+
+// InputError is returned if the Butane config cannot be read.
+type InputError struct {
+	Path string
+	Err  error
+}
+
+func (e *InputError) Error() string {
+	return fmt.Sprintf("failed to read %s: %v", e.Path, e.Err)
+}
+
+func (e *InputError) Unwrap() error {
+	return e.Err
+}
+
+// TranslateError is returned if the Butane config cannot be translated to Ignition.
+// Report holds the errors found in the config, and the warnings in strict mode.
+type TranslateError struct {
+	Report Report
+	Err    error
+}
+
+func (e *TranslateError) Error() string {
+	return fmt.Sprintf("error translating config: %v", e.Err)
+}
+
+func (e *TranslateError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError is returned if the generated Ignition config is invalid. Report holds the
+// errors found in the generated config, and the warnings in strict mode.
+type ValidationError struct {
+	Report Report
+	Err    error
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("generated Ignition config is invalid: %v", e.Err)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// MergeError is returned if the Ignition configs of several Butane fragments cannot be
+// merged. Report holds the objects defined by several fragments in strict mode.
+type MergeError struct {
+	Report Report
+	Err    error
+}
+
+func (e *MergeError) Error() string {
+	return fmt.Sprintf("error merging configs: %v", e.Err)
+}
+
+func (e *MergeError) Unwrap() error {
+	return e.Err
+}
+
+// OutputError is returned if the Ignition config cannot be written.
+type OutputError struct {
+	Path string
+	Err  error
+}
+
+func (e *OutputError) Error() string {
+	return fmt.Sprintf("failed to write config to %s: %v", e.Path, e.Err)
+}
+
+func (e *OutputError) Unwrap() error {
+	return e.Err
 }
 
 type BootstrapInPlaceConfig struct {
 	AssetDir     string
 	IgnitionPath string
-	Input        string
+	// Input is a Butane config applied before the Inputs.
+	//
+	// Deprecated: use Inputs.
+	Input string
+	// Inputs are Butane configs, or directories of them, which are applied in order.
+	Inputs []string
+	// IncludeRules is a YAML file of IncludeRules. If set, the Butane config generated from
+	// them is applied before the Inputs.
+	IncludeRules string
 	Pretty       bool
+	// Template renders the inputs as Go templates with TemplateData before translating them.
+	Template bool
+	// ClusterConfig is the cluster-config ConfigMap manifest the install config is read from in
+	// template mode. It defaults to the one of AssetDir.
+	ClusterConfig string
+	// IgnitionVersion is the Ignition spec version of the output, see SupportedIgnitionVersions.
+	// By default, it is the one implied by the Butane variant and version of the inputs.
+	IgnitionVersion string
+	// Diff prints the changes compared to the existing config at IgnitionPath instead of
+	// writing it, and returns ErrChanged if there are any. With the JSON report format, the
+	// changes are a changes field of the report.
+	Diff bool
+	// CompressThreshold gzip-compresses embedded files of more than this many bytes only, if
+	// positive. Otherwise Butane compresses all files for which it pays off.
+	CompressThreshold int
+	// SizeBudget is the maximum size of the generated Ignition config in bytes, if positive.
+	SizeBudget int
+	// ReportSizes adds the size each embedded file contributes to the Ignition config to the
+	// report. This is implied by CompressThreshold and SizeBudget.
+	ReportSizes bool
+	// Strict treats warnings of the translation and validation as errors.
+	Strict bool
+	// ReportFormat is the format the translation report is written in, text by default.
+	ReportFormat ReportFormat
+	// Out receives the translation report. If not set, os.Stdout is used.
+	Out io.Writer
 }
 type BootstrapInPlaceCommand struct {
 	config BootstrapInPlaceConfig
 }
 
 func NewBootstrapInPlaceCommand(config BootstrapInPlaceConfig) (*BootstrapInPlaceCommand, error) {
+	switch config.ReportFormat {
+	case "", ReportText, ReportJSON:
+	default:
+		return nil, fmt.Errorf("unknown report format %q", config.ReportFormat)
+	}
+	if config.Out == nil {
+		config.Out = os.Stdout
+	}
+	if len(config.ClusterConfig) == 0 {
+		config.ClusterConfig = filepath.Join(config.AssetDir, installconfig.ClusterConfigPath)
+	}
+	if len(config.Input) > 0 {
+		config.Inputs = append([]string{config.Input}, config.Inputs...)
+		config.Input = ""
+	}
 	return &BootstrapInPlaceCommand{
 		config: config,
 	}, nil
@@ -34,38 +163,219 @@ func NewBootstrapInPlaceCommand(config BootstrapInPlaceConfig) (*BootstrapInPlac
 
 // Creating master ignition that will be used by node after reboot
 // Using butane tool (tool that takes yaml and according to it creates ignition):
-// 1. Read actions yaml that has all the data needed by butane to create master.ign
-// 2. Create ignition data
-// 3. Write created data to file
+//  1. Read actions yaml that has all the data needed by butane to create master.ign,
+//     rendering it as template in template mode
+//  2. Create ignition data, merging the ignition data of all fragments if there are several,
+//     converting it to the requested ignition spec version and compressing large files
+//  3. Validate the created data, as a bad master.ign leaves the node unusable after reboot,
+//     and check it against the size budget
+//  4. Write created data to file, atomically and only if it changed, or print the changes
+//     in diff mode
+//
+// The data is canonical JSON with sorted keys, so that the same config always results in the
+// same file. Nothing is written on errors, which are of type *InputError, *TemplateError, *TranslateError,
+// *MergeError, *ConvertError, *ValidationError, *SizeError or *OutputError.
+//
+// Private keys, kubeconfig credentials and the contents of the TLS and auth assets are redacted from
+// the report, the changes and the errors, as they end up in logs.
 func (i *BootstrapInPlaceCommand) Create() error {
-	infile, err := os.Open(i.config.Input)
+	redactor, err := redact.ForAssetDir(i.config.AssetDir)
 	if err != nil {
-		fail("Error occurred while trying to open %s: %v\n", i.config.Input, err)
+		return &InputError{Path: i.config.AssetDir, Err: err}
+	}
+	return redactor.Error(i.create(redactor))
+}
+
+func (i *BootstrapInPlaceCommand) create(redactor *redact.Redactor) error {
+	writeReport := func(report Report) error {
+		return i.writeRedacted(redactor, func(w io.Writer) error { return report.Write(w, i.config.ReportFormat) })
 	}
-	defer infile.Close()
 
-	dataIn, err := ioutil.ReadAll(infile)
+	dataOut, report, err := i.generate()
 	if err != nil {
-		fail("Error occurred while trying to read %s: %v\n", infile.Name(), err)
+		// the error takes precedence over failing to write the report
+		_ = writeReport(report)
+		return err
 	}
 
-	dataOut, r, err := config.TranslateBytes(dataIn, butaneCommon.TranslateBytesOptions{
-		TranslateOptions: butaneCommon.TranslateOptions{FilesDir: i.config.AssetDir},
-		Pretty:           i.config.Pretty},
-	)
-	fmt.Println(r.String())
+	if i.config.Diff {
+		changes, err := diffIgnition(i.config.IgnitionPath, dataOut)
+		if err != nil {
+			_ = writeReport(report)
+			return err
+		}
+		// the report and the changes are written together, so that JSON output is one document
+		if err := i.writeRedacted(redactor, func(w io.Writer) error { return writeDiff(w, report, changes, i.config.ReportFormat) }); err != nil {
+			return fmt.Errorf("failed to write changes: %w", err)
+		}
+		if len(changes) > 0 {
+			return ErrChanged
+		}
+		return nil
+	}
+
+	if err := writeReport(report); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	written, err := writeFileAtomic(i.config.IgnitionPath, dataOut, 0644)
 	if err != nil {
-		fail("Error translating config: %v\n", err)
+		return &OutputError{Path: i.config.IgnitionPath, Err: err}
+	}
+	if !written && i.config.ReportFormat != ReportJSON {
+		fmt.Fprintf(i.config.Out, "%s is unchanged, not writing it\n", i.config.IgnitionPath)
+	}
+	return nil
+}
+
+// writeRedacted writes the output of write to Out with all secrets masked.
+func (i *BootstrapInPlaceCommand) writeRedacted(redactor *redact.Redactor, write func(w io.Writer) error) error {
+	buf := &bytes.Buffer{}
+	if err := write(buf); err != nil {
+		return err
 	}
+	_, err := i.config.Out.Write(redactor.Bytes(buf.Bytes()))
+	return err
+}
+
+type butaneInput struct {
+	source string
+	data   []byte
+}
 
-	outfile, err := os.OpenFile(i.config.IgnitionPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+// butaneInputs returns the Butane config generated from the include rules, if any, followed
+// by the input files, rendered as templates in template mode.
+func (i *BootstrapInPlaceCommand) butaneInputs() ([]butaneInput, error) {
+	var inputs []butaneInput
+	if len(i.config.IncludeRules) > 0 {
+		rules, err := LoadIncludeRules(i.config.IncludeRules)
+		if err != nil {
+			return nil, &InputError{Path: i.config.IncludeRules, Err: err}
+		}
+		data, err := GenerateButane(i.config.AssetDir, *rules)
+		if err != nil {
+			return nil, &InputError{Path: i.config.IncludeRules, Err: err}
+		}
+		inputs = append(inputs, butaneInput{source: i.config.IncludeRules, data: data})
+	}
+
+	files, err := inputFiles(i.config.Inputs)
 	if err != nil {
-		fail("Failed to open %s: %v\n", i.config.IgnitionPath, err)
+		return nil, err
+	}
+	var templateData *TemplateData
+	if i.config.Template && len(files) > 0 {
+		if templateData, err = loadTemplateData(i.config.ClusterConfig); err != nil {
+			return nil, &InputError{Path: i.config.ClusterConfig, Err: err}
+		}
+	}
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, &InputError{Path: file, Err: err}
+		}
+		if templateData != nil {
+			if data, err = renderTemplate(filepath.Base(file), data, i.config.AssetDir, templateData); err != nil {
+				return nil, &TemplateError{Path: file, Err: err}
+			}
+		}
+		inputs = append(inputs, butaneInput{source: file, data: data})
 	}
-	defer outfile.Close()
 
-	if _, err := outfile.Write(append(dataOut, '\n')); err != nil {
-		fail("Failed to write config to %s: %v\n", outfile.Name(), err)
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("no input files or include rules given")
 	}
-	return nil
+	return inputs, nil
+}
+
+// generate returns the validated ignition data, as written to the ignition file, and the report
+// of all stages.
+func (i *BootstrapInPlaceCommand) generate() ([]byte, Report, error) {
+	var translateReport, mergeReport, validateReport, sizeReport Report
+	fullReport := func() Report {
+		var entries []ReportEntry
+		entries = append(entries, translateReport.Entries...)
+		entries = append(entries, mergeReport.Entries...)
+		entries = append(entries, validateReport.Entries...)
+		entries = append(entries, sizeReport.Entries...)
+		return Report{Entries: entries}
+	}
+
+	inputs, err := i.butaneInputs()
+	if err != nil {
+		return nil, fullReport(), err
+	}
+
+	fragments := make([]fragment, 0, len(inputs))
+	for _, in := range inputs {
+		input, dataIn := in.source, in.data
+
+		dataOut, r, err := config.TranslateBytes(dataIn, butaneCommon.TranslateBytesOptions{
+			TranslateOptions: butaneCommon.TranslateOptions{
+				FilesDir:                  i.config.AssetDir,
+				NoResourceAutoCompression: i.config.CompressThreshold > 0,
+			},
+			Pretty: i.config.Pretty},
+		)
+		source := ""
+		if len(inputs) > 1 {
+			source = input
+		}
+		fragmentReport := newReport(StageTranslate, source, r)
+		translateReport.Entries = append(translateReport.Entries, fragmentReport.Entries...)
+		if err != nil {
+			if len(source) > 0 {
+				err = fmt.Errorf("%s: %w", source, err)
+			}
+			return nil, fullReport(), &TranslateError{Report: fragmentReport, Err: err}
+		}
+		fragments = append(fragments, fragment{source: input, ignition: dataOut})
+	}
+
+	dataOut := fragments[0].ignition
+	if len(fragments) > 1 {
+		dataOut, mergeReport, err = mergeFragments(fragments, i.config.Pretty)
+		if err != nil {
+			return nil, fullReport(), &MergeError{Report: mergeReport, Err: err}
+		}
+	}
+
+	if len(i.config.IgnitionVersion) > 0 {
+		if dataOut, err = convertIgnition(dataOut, i.config.IgnitionVersion, i.config.Pretty); err != nil {
+			return nil, fullReport(), err
+		}
+	}
+
+	if i.config.CompressThreshold > 0 {
+		if dataOut, err = compressFiles(dataOut, i.config.CompressThreshold, i.config.Pretty); err != nil {
+			return nil, fullReport(), err
+		}
+	}
+
+	// Parse validates the config, e.g. the spec version, duplicate paths and file modes.
+	_, r, err := ignition.Parse(dataOut)
+	validateReport = newReport(StageValidate, "", r)
+	switch {
+	case err != nil:
+		return nil, fullReport(), &ValidationError{Report: validateReport, Err: err}
+	case i.config.Strict && len(translateReport.Entries) > 0:
+		return nil, fullReport(), &TranslateError{Report: translateReport, Err: ErrStrict}
+	case i.config.Strict && len(mergeReport.Entries) > 0:
+		return nil, fullReport(), &MergeError{Report: mergeReport, Err: ErrStrict}
+	case i.config.Strict && len(validateReport.Entries) > 0:
+		return nil, fullReport(), &ValidationError{Report: validateReport, Err: ErrStrict}
+	}
+
+	dataOut, err = canonicalJSON(dataOut, i.config.Pretty)
+	if err != nil {
+		return nil, fullReport(), err
+	}
+	// the budget applies to the bytes written, including the trailing newline
+	dataOut = append(dataOut, '\n')
+
+	if i.config.ReportSizes || i.config.CompressThreshold > 0 || i.config.SizeBudget > 0 {
+		if sizeReport, err = fileSizes(dataOut, i.config.SizeBudget); err != nil {
+			return nil, fullReport(), err
+		}
+	}
+	return dataOut, fullReport(), nil
 }
@@ -0,0 +1,164 @@
+package bootstrapinplace
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCreate(t *testing.T) {
+	tests := []struct {
+		name          string
+		input         string
+		noInput       bool
+		strict        bool
+		expectedErr   interface{}
+		expectedEntry *ReportEntry
+	}{
+		{
+			name:  "valid config",
+			input: "variant: fcos\nversion: 1.1.0\nstorage:\n  files:\n  - path: /etc/foo\n",
+		},
+		{
+			name:  "warnings",
+			input: "variant: fcos\nversion: 1.1.0\nstorage:\n  files:\n  - path: /etc/foo\n    mode: 644\n",
+			expectedEntry: &ReportEntry{
+				Stage:   StageTranslate,
+				Kind:    "warning",
+				Message: "unreasonable mode would be reasonable if specified in octal; remember to add a leading zero",
+				Path:    "$.storage.files.0.mode",
+				Line:    6,
+				Column:  11,
+			},
+		},
+		{
+			name:        "warnings in strict mode",
+			input:       "variant: fcos\nversion: 1.1.0\nstorage:\n  files:\n  - path: /etc/foo\n    mode: 644\n",
+			strict:      true,
+			expectedErr: &TranslateError{},
+		},
+		{
+			name:        "missing input",
+			noInput:     true,
+			expectedErr: &InputError{},
+		},
+		{
+			name:        "invalid config",
+			input:       "variant: fcos\nversion: 1.1.0\nstorage:\n  files:\n  - path: etc/foo\n",
+			expectedErr: &TranslateError{},
+			expectedEntry: &ReportEntry{
+				Stage:   StageTranslate,
+				Kind:    "error",
+				Message: "path not absolute",
+				Path:    "$.storage.files.0.path",
+				Line:    5,
+				Column:  11,
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			input := filepath.Join(dir, "input.bu")
+			if !test.noInput {
+				if err := os.WriteFile(input, []byte(test.input), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			out := &bytes.Buffer{}
+			bip, err := NewBootstrapInPlaceCommand(BootstrapInPlaceConfig{
+				AssetDir:     dir,
+				IgnitionPath: filepath.Join(dir, "out.ign"),
+				Inputs:       []string{input},
+				Strict:       test.strict,
+				ReportFormat: ReportJSON,
+				Out:          out,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = bip.Create()
+			switch expected := test.expectedErr.(type) {
+			case nil:
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if _, err := os.Stat(filepath.Join(dir, "out.ign")); err != nil {
+					t.Errorf("expected ignition output: %v", err)
+				}
+			case *InputError:
+				if !errors.As(err, &expected) {
+					t.Fatalf("expected InputError, got %v", err)
+				}
+			case *TranslateError:
+				if !errors.As(err, &expected) {
+					t.Fatalf("expected TranslateError, got %v", err)
+				}
+				if test.strict != errors.Is(err, ErrStrict) {
+					t.Errorf("expected strict mode error to be %v, got %v", test.strict, err)
+				}
+				if !test.strict && !expected.Report.IsFatal() {
+					t.Errorf("expected fatal report, got %v", expected.Report)
+				}
+			}
+			if test.expectedErr != nil {
+				if _, err := os.Stat(filepath.Join(dir, "out.ign")); !os.IsNotExist(err) {
+					t.Errorf("expected no ignition output on error, got %v", err)
+				}
+			}
+
+			if test.expectedEntry != nil {
+				var report Report
+				if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+					t.Fatalf("failed to parse JSON report %q: %v", out.String(), err)
+				}
+				found := false
+				for _, e := range report.Entries {
+					if e == *test.expectedEntry {
+						found = true
+					}
+				}
+				if !found {
+					t.Errorf("expected report entry %+v, got %+v", *test.expectedEntry, report.Entries)
+				}
+			}
+		})
+	}
+}
+
+func TestCreateDeprecatedInput(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"input.bu": "variant: fcos\nversion: 1.1.0\nstorage:\n  files:\n  - path: /etc/foo\n",
+		"more.bu":  "variant: fcos\nversion: 1.1.0\nstorage:\n  files:\n  - path: /etc/bar\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ignitionPath := filepath.Join(dir, "out.ign")
+	bip, err := NewBootstrapInPlaceCommand(BootstrapInPlaceConfig{
+		AssetDir:     dir,
+		IgnitionPath: ignitionPath,
+		Input:        filepath.Join(dir, "input.bu"),
+		Inputs:       []string{filepath.Join(dir, "more.bu")},
+		Out:          &bytes.Buffer{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bip.Create(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(ignitionPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(data, []byte("/etc/foo")) || !bytes.Contains(data, []byte("/etc/bar")) {
+		t.Errorf("expected Input to be applied along with Inputs, got %s", data)
+	}
+}
@@ -0,0 +1,230 @@
+package bootstrapinplace
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	ignition "github.com/coreos/ignition/v2/config"
+	"github.com/coreos/ignition/v2/config/util"
+	exp "github.com/coreos/ignition/v2/config/v3_4_experimental/types"
+)
+
+// ErrChanged is returned in diff mode if the generated Ignition config differs from the
+// existing one.
+var ErrChanged = errors.New("generated Ignition config differs from the existing one")
+
+// ConfigChange is a difference between the existing and the generated Ignition config.
+type ConfigChange struct {
+	// Op is "+" for added, "-" for removed and "~" for changed objects.
+	Op string `json:"op"`
+	// Kind is one of file, directory, link, unit, user or config for anything else.
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	// Fields are the changed fields of changed objects.
+	Fields []string `json:"fields,omitempty"`
+}
+
+func (c ConfigChange) String() string {
+	if len(c.Fields) == 0 {
+		return fmt.Sprintf("%s %s %s", c.Op, c.Kind, c.Name)
+	}
+	return fmt.Sprintf("%s %s %s: %s", c.Op, c.Kind, c.Name, strings.Join(c.Fields, ", "))
+}
+
+// diffIgnition returns the changes of the generated Ignition config compared to the existing
+// one at path. A missing existing file is treated like an empty config.
+func diffIgnition(path string, generated []byte) ([]ConfigChange, error) {
+	existing, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		existing = nil
+	} else if err != nil {
+		return nil, &InputError{Path: path, Err: err}
+	}
+
+	oldConfig, oldVersion := exp.Config{}, ""
+	if existing != nil {
+		v, _, err := util.GetConfigVersion(existing)
+		if err != nil {
+			return nil, &InputError{Path: path, Err: err}
+		}
+		if oldConfig, _, err = ignition.Parse(existing); err != nil {
+			return nil, &InputError{Path: path, Err: err}
+		}
+		oldVersion = v.String()
+	}
+	v, _, err := util.GetConfigVersion(generated)
+	if err != nil {
+		return nil, err
+	}
+	newConfig, _, err := ignition.Parse(generated)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := diffConfigs(oldConfig, newConfig)
+	if existing != nil && oldVersion != v.String() {
+		changes = append([]ConfigChange{{Op: "~", Kind: "config", Name: "version", Fields: []string{oldVersion + " -> " + v.String()}}}, changes...)
+	}
+	return changes, nil
+}
+
+// diffConfigs returns the added, removed and changed files, directories, links, units and
+// users, followed by a change of all other config, if any.
+func diffConfigs(oldConfig, newConfig exp.Config) []ConfigChange {
+	var changes []ConfigChange
+	changes = append(changes, diffObjects("file", filesByPath(oldConfig), filesByPath(newConfig))...)
+	changes = append(changes, diffObjects("directory", directoriesByPath(oldConfig), directoriesByPath(newConfig))...)
+	changes = append(changes, diffObjects("link", linksByPath(oldConfig), linksByPath(newConfig))...)
+	changes = append(changes, diffObjects("unit", unitsByName(oldConfig), unitsByName(newConfig))...)
+	changes = append(changes, diffObjects("user", usersByName(oldConfig), usersByName(newConfig))...)
+
+	oldRest, newRest := withoutObjects(oldConfig), withoutObjects(newConfig)
+	if fields := changedFields(oldRest, newRest); len(fields) > 0 {
+		changes = append(changes, ConfigChange{Op: "~", Kind: "config", Name: "other", Fields: fields})
+	}
+	return changes
+}
+
+func diffObjects(kind string, oldObjects, newObjects map[string]interface{}) []ConfigChange {
+	names := map[string]bool{}
+	for name := range oldObjects {
+		names[name] = true
+	}
+	for name := range newObjects {
+		names[name] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	var changes []ConfigChange
+	for _, name := range sorted {
+		oldObject, inOld := oldObjects[name]
+		newObject, inNew := newObjects[name]
+		switch {
+		case !inOld:
+			changes = append(changes, ConfigChange{Op: "+", Kind: kind, Name: name})
+		case !inNew:
+			changes = append(changes, ConfigChange{Op: "-", Kind: kind, Name: name})
+		default:
+			if fields := changedFields(oldObject, newObject); len(fields) > 0 {
+				changes = append(changes, ConfigChange{Op: "~", Kind: kind, Name: name, Fields: fields})
+			}
+		}
+	}
+	return changes
+}
+
+// changedFields returns the sorted top-level JSON fields which differ between a and b.
+func changedFields(a, b interface{}) []string {
+	aFields, bFields := jsonFields(a), jsonFields(b)
+	var fields []string
+	for k, v := range aFields {
+		if !reflect.DeepEqual(v, bFields[k]) {
+			fields = append(fields, k)
+		}
+	}
+	for k := range bFields {
+		if _, ok := aFields[k]; !ok {
+			fields = append(fields, k)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func jsonFields(v interface{}) map[string]interface{} {
+	fields := map[string]interface{}{}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fields
+	}
+	_ = json.Unmarshal(data, &fields)
+	// struct fields are marshalled even if empty
+	pruneEmptyObjects(fields)
+	return fields
+}
+
+func filesByPath(cfg exp.Config) map[string]interface{} {
+	objects := map[string]interface{}{}
+	for _, f := range cfg.Storage.Files {
+		objects[f.Path] = f
+	}
+	return objects
+}
+
+func directoriesByPath(cfg exp.Config) map[string]interface{} {
+	objects := map[string]interface{}{}
+	for _, d := range cfg.Storage.Directories {
+		objects[d.Path] = d
+	}
+	return objects
+}
+
+func linksByPath(cfg exp.Config) map[string]interface{} {
+	objects := map[string]interface{}{}
+	for _, l := range cfg.Storage.Links {
+		objects[l.Path] = l
+	}
+	return objects
+}
+
+func unitsByName(cfg exp.Config) map[string]interface{} {
+	objects := map[string]interface{}{}
+	for _, u := range cfg.Systemd.Units {
+		objects[u.Name] = u
+	}
+	return objects
+}
+
+func usersByName(cfg exp.Config) map[string]interface{} {
+	objects := map[string]interface{}{}
+	for _, u := range cfg.Passwd.Users {
+		objects[u.Name] = u
+	}
+	return objects
+}
+
+// withoutObjects returns the config without the objects diffed one by one.
+func withoutObjects(cfg exp.Config) exp.Config {
+	cfg.Storage.Files = nil
+	cfg.Storage.Directories = nil
+	cfg.Storage.Links = nil
+	cfg.Systemd.Units = nil
+	cfg.Passwd.Users = nil
+	return cfg
+}
+
+// writeDiff writes the report followed by the changes to w in the given format. In JSON, both
+// are written as a single document, the report with an additional changes field.
+func writeDiff(w io.Writer, r Report, changes []ConfigChange, format ReportFormat) error {
+	if format == ReportJSON {
+		if changes == nil {
+			changes = []ConfigChange{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(struct {
+			Report
+			Changes []ConfigChange `json:"changes"`
+		}{r, changes})
+	}
+	if err := r.Write(w, format); err != nil {
+		return err
+	}
+	for _, c := range changes {
+		if _, err := fmt.Fprintln(w, c.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
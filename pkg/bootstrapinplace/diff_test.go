@@ -0,0 +1,151 @@
+package bootstrapinplace
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	exp "github.com/coreos/ignition/v2/config/v3_4_experimental/types"
+)
+
+func TestDiffConfigs(t *testing.T) {
+	mode := 0644
+	enabled := true
+	contents := "data:,new"
+
+	oldConfig := exp.Config{}
+	oldConfig.Storage.Files = []exp.File{
+		{Node: exp.Node{Path: "/etc/removed"}},
+		{Node: exp.Node{Path: "/etc/changed"}},
+		{Node: exp.Node{Path: "/etc/same"}},
+	}
+	oldConfig.Systemd.Units = []exp.Unit{{Name: "a.service"}}
+	oldConfig.Passwd.Users = []exp.PasswdUser{{Name: "core"}}
+
+	newConfig := exp.Config{}
+	newConfig.Storage.Files = []exp.File{
+		{Node: exp.Node{Path: "/etc/added"}},
+		{Node: exp.Node{Path: "/etc/changed"}, FileEmbedded1: exp.FileEmbedded1{Mode: &mode, Contents: exp.Resource{Source: &contents}}},
+		{Node: exp.Node{Path: "/etc/same"}},
+	}
+	newConfig.Systemd.Units = []exp.Unit{{Name: "a.service", Enabled: &enabled}}
+	newConfig.Passwd.Users = []exp.PasswdUser{{Name: "core"}}
+	newConfig.KernelArguments.ShouldExist = []exp.KernelArgument{"foo"}
+
+	expected := []string{
+		"+ file /etc/added",
+		"~ file /etc/changed: contents, mode",
+		"- file /etc/removed",
+		"~ unit a.service: enabled",
+		"~ config other: kernelArguments",
+	}
+	var got []string
+	for _, c := range diffConfigs(oldConfig, newConfig) {
+		got = append(got, c.String())
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected changes %q, got %q", expected, got)
+	}
+
+	if changes := diffConfigs(newConfig, newConfig); len(changes) != 0 {
+		t.Errorf("expected no changes, got %v", changes)
+	}
+}
+
+func TestCreateWritesOnlyChanges(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "input.bu")
+	ignitionPath := filepath.Join(dir, "out.ign")
+	create := func(content string, diff bool) (string, error) {
+		if err := os.WriteFile(input, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		out := &bytes.Buffer{}
+		bip, err := NewBootstrapInPlaceCommand(BootstrapInPlaceConfig{
+			AssetDir:     dir,
+			IgnitionPath: ignitionPath,
+			Inputs:       []string{input},
+			Pretty:       true,
+			Diff:         diff,
+			Out:          out,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = bip.Create()
+		return out.String(), err
+	}
+	config := "variant: fcos\nversion: 1.1.0\nstorage:\n  files:\n  - path: /etc/foo\n"
+
+	out, err := create(config, true)
+	if !errors.Is(err, ErrChanged) || !strings.Contains(out, "+ file /etc/foo") {
+		t.Errorf("expected the file to be added in diff mode, got %v: %s", err, out)
+	}
+	if _, err := os.Stat(ignitionPath); !os.IsNotExist(err) {
+		t.Fatalf("expected no output in diff mode, got %v", err)
+	}
+
+	if _, err := create(config, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	first, err := os.ReadFile(ignitionPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(first, []byte("{\n  \"ignition\": {\n    \"version\": \"3.1.0\"\n  },\n  \"storage\"")) {
+		t.Errorf("expected canonical JSON with sorted keys, got %s", first)
+	}
+
+	out, err = create(config, false)
+	if err != nil || !strings.Contains(out, "is unchanged") {
+		t.Errorf("expected the unchanged output not to be written, got %v: %s", err, out)
+	}
+	if out, err := create(config, true); err != nil || strings.TrimSpace(out) != "" {
+		t.Errorf("expected no changes in diff mode, got %v: %s", err, out)
+	}
+
+	// in JSON, the report and the changes are a single document
+	jsonOut := &bytes.Buffer{}
+	bip, err := NewBootstrapInPlaceCommand(BootstrapInPlaceConfig{
+		AssetDir:     dir,
+		IgnitionPath: ignitionPath,
+		Inputs:       []string{input},
+		Diff:         true,
+		ReportFormat: ReportJSON,
+		ReportSizes:  true,
+		Out:          jsonOut,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bip.Create(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var result struct {
+		Entries []ReportEntry  `json:"entries"`
+		Changes []ConfigChange `json:"changes"`
+	}
+	if err := json.Unmarshal(jsonOut.Bytes(), &result); err != nil {
+		t.Fatalf("expected a single JSON document, got %v: %s", err, jsonOut)
+	}
+	if len(result.Entries) == 0 || result.Changes == nil || len(result.Changes) != 0 {
+		t.Errorf("expected the size report and no changes, got %+v", result)
+	}
+
+	out, err = create(config+"    mode: 0600\n", true)
+	if !errors.Is(err, ErrChanged) || !strings.Contains(out, "~ file /etc/foo: mode") {
+		t.Errorf("expected the file mode to be changed in diff mode, got %v: %s", err, out)
+	}
+	second, err := os.ReadFile(ignitionPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(first, second) {
+		t.Errorf("expected the output not to be written in diff mode")
+	}
+}
@@ -0,0 +1,138 @@
+package bootstrapinplace
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/coreos/go-semver/semver"
+	ignition "github.com/coreos/ignition/v2/config"
+	"github.com/coreos/ignition/v2/config/util"
+	v3_0 "github.com/coreos/ignition/v2/config/v3_0/types"
+	v3_1 "github.com/coreos/ignition/v2/config/v3_1/types"
+	v3_2 "github.com/coreos/ignition/v2/config/v3_2/types"
+	v3_3 "github.com/coreos/ignition/v2/config/v3_3/types"
+	exp "github.com/coreos/ignition/v2/config/v3_4_experimental/types"
+)
+
+// ignitionConfigs returns an empty config of each supported Ignition spec version.
+var ignitionConfigs = map[semver.Version]func() interface{}{
+	v3_0.MaxVersion: func() interface{} { return &v3_0.Config{} },
+	v3_1.MaxVersion: func() interface{} { return &v3_1.Config{} },
+	v3_2.MaxVersion: func() interface{} { return &v3_2.Config{} },
+	v3_3.MaxVersion: func() interface{} { return &v3_3.Config{} },
+	exp.MaxVersion:  func() interface{} { return &exp.Config{} },
+}
+
+// SupportedIgnitionVersions returns the Ignition spec versions the output can be converted to.
+func SupportedIgnitionVersions() []string {
+	var versions []semver.Version
+	for v := range ignitionConfigs {
+		versions = append(versions, v)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].LessThan(versions[j]) })
+	names := make([]string, 0, len(versions))
+	for _, v := range versions {
+		names = append(names, v.String())
+	}
+	return names
+}
+
+// ConvertError is returned if the Ignition config cannot be converted to the requested
+// Ignition spec version.
+type ConvertError struct {
+	Version string
+	Err     error
+}
+
+func (e *ConvertError) Error() string {
+	return fmt.Sprintf("failed to convert config to Ignition spec version %s: %v", e.Version, e.Err)
+}
+
+func (e *ConvertError) Unwrap() error {
+	return e.Err
+}
+
+// convertIgnition converts the valid Ignition config data to the given spec version, if it
+// is of another one.
+func convertIgnition(data []byte, version string, pretty bool) ([]byte, error) {
+	target, err := semver.NewVersion(version)
+	if err != nil {
+		return nil, &ConvertError{Version: version, Err: err}
+	}
+	current, _, err := util.GetConfigVersion(data)
+	if err != nil {
+		return nil, &ConvertError{Version: version, Err: err}
+	}
+	if current.Equal(*target) {
+		return data, nil
+	}
+	cfg, _, err := ignition.Parse(data)
+	if err != nil {
+		return nil, &ConvertError{Version: version, Err: err}
+	}
+	converted, err := convertConfig(cfg, *target, pretty)
+	if err != nil {
+		return nil, &ConvertError{Version: version, Err: err}
+	}
+	return converted, nil
+}
+
+// convertConfig converts the config to the given Ignition spec version and marshals it. As the
+// spec versions only add fields, this fails if the config uses fields the version does not know.
+func convertConfig(cfg exp.Config, version semver.Version, pretty bool) ([]byte, error) {
+	newConfig, ok := ignitionConfigs[version]
+	if !ok {
+		return nil, fmt.Errorf("unsupported Ignition spec version %s", version)
+	}
+	cfg.Ignition.Version = version.String()
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	// struct fields are marshalled even if empty, which older versions may not know
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	pruneEmptyObjects(raw)
+	if data, err = json.Marshal(raw); err != nil {
+		return nil, err
+	}
+
+	converted := newConfig()
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(converted); err != nil {
+		return nil, fmt.Errorf("config uses features which spec version %s cannot express: %v", version, err)
+	}
+	return marshalConfig(converted, pretty)
+}
+
+// pruneEmptyObjects recursively removes the fields of obj which are objects without fields.
+func pruneEmptyObjects(obj map[string]interface{}) {
+	for k, v := range obj {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			pruneEmptyObjects(v)
+			if len(v) == 0 {
+				delete(obj, k)
+			}
+		case []interface{}:
+			for _, item := range v {
+				if item, ok := item.(map[string]interface{}); ok {
+					pruneEmptyObjects(item)
+				}
+			}
+		}
+	}
+}
+
+// marshalConfig marshals the config like butane does.
+func marshalConfig(cfg interface{}, pretty bool) ([]byte, error) {
+	if pretty {
+		return json.MarshalIndent(cfg, "", "  ")
+	}
+	return json.Marshal(cfg)
+}
@@ -0,0 +1,119 @@
+package bootstrapinplace
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	v3_2 "github.com/coreos/ignition/v2/config/v3_2/types"
+	v3_3 "github.com/coreos/ignition/v2/config/v3_3/types"
+	exp "github.com/coreos/ignition/v2/config/v3_4_experimental/types"
+)
+
+func TestConvertConfig(t *testing.T) {
+	cfg := exp.Config{}
+	cfg.Storage.Files = []exp.File{{Node: exp.Node{Path: "/etc/foo"}}}
+	data, err := convertConfig(cfg, v3_2.MaxVersion, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	converted := v3_2.Config{}
+	if err := json.Unmarshal(data, &converted); err != nil {
+		t.Fatal(err)
+	}
+	if converted.Ignition.Version != "3.2.0" || len(converted.Storage.Files) != 1 || converted.Storage.Files[0].Path != "/etc/foo" {
+		t.Errorf("unexpected converted config %s", data)
+	}
+
+	cfg.KernelArguments.ShouldExist = []exp.KernelArgument{"foo"}
+	if _, err := convertConfig(cfg, v3_2.MaxVersion, false); err == nil || !strings.Contains(err.Error(), "kernelArguments") {
+		t.Errorf("expected kernel arguments to be rejected for spec version 3.2.0, got %v", err)
+	}
+	if _, err := convertConfig(cfg, v3_3.MaxVersion, false); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestCreateIgnitionVersion(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		version     string
+		expectedErr string
+	}{
+		{
+			name:    "down",
+			input:   "variant: fcos\nversion: 1.3.0\nstorage:\n  files:\n  - path: /etc/foo\n",
+			version: "3.0.0",
+		},
+		{
+			name:    "up",
+			input:   "variant: fcos\nversion: 1.0.0\nstorage:\n  files:\n  - path: /etc/foo\n",
+			version: "3.3.0",
+		},
+		{
+			name:    "same",
+			input:   "variant: fcos\nversion: 1.3.0\nstorage:\n  files:\n  - path: /etc/foo\n",
+			version: "3.2.0",
+		},
+		{
+			name:        "unsupported feature",
+			input:       "variant: fcos\nversion: 1.4.0\nkernel_arguments:\n  should_exist:\n  - foo\n",
+			version:     "3.2.0",
+			expectedErr: "kernelArguments",
+		},
+		{
+			name:        "unsupported version",
+			input:       "variant: fcos\nversion: 1.3.0\n",
+			version:     "2.2.0",
+			expectedErr: "unsupported Ignition spec version",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			input := filepath.Join(dir, "input.bu")
+			if err := os.WriteFile(input, []byte(test.input), 0644); err != nil {
+				t.Fatal(err)
+			}
+			ignitionPath := filepath.Join(dir, "out.ign")
+			bip, err := NewBootstrapInPlaceCommand(BootstrapInPlaceConfig{
+				AssetDir:        dir,
+				IgnitionPath:    ignitionPath,
+				Inputs:          []string{input},
+				IgnitionVersion: test.version,
+				Out:             io.Discard,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = bip.Create()
+			if test.expectedErr != "" {
+				var convertErr *ConvertError
+				if !errors.As(err, &convertErr) || !strings.Contains(err.Error(), test.expectedErr) {
+					t.Fatalf("expected ConvertError containing %q, got %v", test.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			data, err := os.ReadFile(ignitionPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cfg := exp.Config{}
+			if err := json.Unmarshal(data, &cfg); err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Ignition.Version != test.version || len(cfg.Storage.Files) != 1 {
+				t.Errorf("expected spec version %s with one file, got %s", test.version, data)
+			}
+		})
+	}
+}
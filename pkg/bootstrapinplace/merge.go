@@ -0,0 +1,145 @@
+package bootstrapinplace
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/coreos/go-semver/semver"
+	ignition "github.com/coreos/ignition/v2/config"
+	"github.com/coreos/ignition/v2/config/merge"
+	"github.com/coreos/ignition/v2/config/util"
+	exp "github.com/coreos/ignition/v2/config/v3_4_experimental/types"
+	"github.com/coreos/vcontext/report"
+)
+
+// fragment is the Ignition config translated from one Butane fragment.
+type fragment struct {
+	source   string
+	ignition []byte
+}
+
+// inputFiles returns the Butane fragments of the inputs in order. Directories are expanded
+// to their regular files in lexical order, skipping hidden files.
+func inputFiles(inputs []string) ([]string, error) {
+	var files []string
+	for _, input := range inputs {
+		info, err := os.Stat(input)
+		if err != nil {
+			return nil, &InputError{Path: input, Err: err}
+		}
+		if !info.IsDir() {
+			files = append(files, input)
+			continue
+		}
+
+		entries, err := ioutil.ReadDir(input)
+		if err != nil {
+			return nil, &InputError{Path: input, Err: err}
+		}
+		found := false
+		for _, e := range entries {
+			if !e.Mode().IsRegular() || strings.HasPrefix(e.Name(), ".") {
+				continue
+			}
+			files = append(files, filepath.Join(input, e.Name()))
+			found = true
+		}
+		if !found {
+			return nil, &InputError{Path: input, Err: fmt.Errorf("no Butane fragments found")}
+		}
+	}
+	return files, nil
+}
+
+// mergeFragments merges the Ignition configs of the fragments with Ignition's merge semantics,
+// later fragments taking precedence, into a config of the highest spec version of the fragments.
+// Objects defined by several fragments are reported as warnings.
+func mergeFragments(fragments []fragment, pretty bool) ([]byte, Report, error) {
+	var (
+		conflicts Report
+		merged    exp.Config
+		version   semver.Version
+		owners    = map[string]string{}
+	)
+	for n, f := range fragments {
+		v, _, err := util.GetConfigVersion(f.ignition)
+		if err != nil {
+			return nil, conflicts, fmt.Errorf("%s: %w", f.source, err)
+		}
+		cfg, r, err := ignition.Parse(f.ignition)
+		if err != nil {
+			conflicts.Entries = append(conflicts.Entries, newReport(StageMerge, f.source, r).Entries...)
+			return nil, conflicts, fmt.Errorf("%s: %w", f.source, err)
+		}
+
+		for _, key := range configKeys(cfg) {
+			if owner, ok := owners[key]; ok {
+				conflicts.Entries = append(conflicts.Entries, ReportEntry{
+					Stage:   StageMerge,
+					Source:  f.source,
+					Kind:    report.Warn.String(),
+					Message: fmt.Sprintf("%s is also defined in %s, merged with %s taking precedence", key, owner, f.source),
+				})
+			}
+			owners[key] = f.source
+		}
+
+		if n == 0 {
+			merged, version = cfg, v
+			continue
+		}
+		result, _ := merge.MergeStructTranscribe(merged, cfg)
+		merged = result.(exp.Config)
+		if version.LessThan(v) {
+			version = v
+		}
+	}
+
+	data, err := convertConfig(merged, version, pretty)
+	return data, conflicts, err
+}
+
+// configKeys returns descriptions of the objects of the config which Ignition merges by key.
+func configKeys(cfg exp.Config) []string {
+	var keys []string
+	add := func(format string, args ...interface{}) {
+		keys = append(keys, fmt.Sprintf(format, args...))
+	}
+	// files, directories and links share their keys
+	for _, f := range cfg.Storage.Files {
+		add("storage path %s", f.Path)
+	}
+	for _, d := range cfg.Storage.Directories {
+		add("storage path %s", d.Path)
+	}
+	for _, l := range cfg.Storage.Links {
+		add("storage path %s", l.Path)
+	}
+	for _, d := range cfg.Storage.Disks {
+		add("storage disk %s", d.Device)
+	}
+	for _, fs := range cfg.Storage.Filesystems {
+		if fs.Device != "" {
+			add("storage filesystem %s", fs.Device)
+		}
+	}
+	for _, l := range cfg.Storage.Luks {
+		add("storage luks device %s", l.Name)
+	}
+	for _, r := range cfg.Storage.Raid {
+		add("storage raid %s", r.Name)
+	}
+	for _, u := range cfg.Systemd.Units {
+		add("systemd unit %s", u.Name)
+	}
+	for _, u := range cfg.Passwd.Users {
+		add("passwd user %s", u.Name)
+	}
+	for _, g := range cfg.Passwd.Groups {
+		add("passwd group %s", g.Name)
+	}
+	return keys
+}
@@ -0,0 +1,94 @@
+package bootstrapinplace
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCreateMergesFragments(t *testing.T) {
+	dir := t.TempDir()
+	fragments := map[string]string{
+		"10-installer.bu": "variant: fcos\nversion: 1.1.0\nstorage:\n  files:\n  - path: /etc/installer\n    contents:\n      inline: installer\n  - path: /etc/shared\n    contents:\n      inline: installer\n",
+		"20-platform.bu":  "variant: fcos\nversion: 1.3.0\nstorage:\n  files:\n  - path: /etc/shared\n    contents:\n      inline: platform\nsystemd:\n  units:\n  - name: platform.service\n    enabled: true\n",
+		".hidden.bu":      "not a butane config",
+	}
+	for name, content := range fragments {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, strict := range []bool{false, true} {
+		out := &bytes.Buffer{}
+		ignitionPath := filepath.Join(t.TempDir(), "out.ign")
+		bip, err := NewBootstrapInPlaceCommand(BootstrapInPlaceConfig{
+			AssetDir:     dir,
+			IgnitionPath: ignitionPath,
+			Inputs:       []string{dir},
+			Strict:       strict,
+			Out:          out,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = bip.Create()
+		if !strings.Contains(out.String(), filepath.Join(dir, "20-platform.bu")+": warning: storage path /etc/shared is also defined in "+filepath.Join(dir, "10-installer.bu")) {
+			t.Errorf("expected conflict to be reported by source file, got %q", out.String())
+		}
+		if strict {
+			var mergeErr *MergeError
+			if !errors.As(err, &mergeErr) || !errors.Is(err, ErrStrict) {
+				t.Errorf("expected strict MergeError, got %v", err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		data, err := os.ReadFile(ignitionPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var cfg struct {
+			Ignition struct {
+				Version string `json:"version"`
+			} `json:"ignition"`
+			Storage struct {
+				Files []struct {
+					Path     string `json:"path"`
+					Contents struct {
+						Source string `json:"source"`
+					} `json:"contents"`
+				} `json:"files"`
+			} `json:"storage"`
+			Systemd struct {
+				Units []struct {
+					Name string `json:"name"`
+				} `json:"units"`
+			} `json:"systemd"`
+		}
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			t.Fatal(err)
+		}
+		if cfg.Ignition.Version != "3.2.0" {
+			t.Errorf("expected the highest spec version 3.2.0, got %s", cfg.Ignition.Version)
+		}
+		contents := map[string]string{}
+		for _, f := range cfg.Storage.Files {
+			contents[f.Path] = f.Contents.Source
+		}
+		if len(contents) != 2 || contents["/etc/installer"] != "data:,installer" || contents["/etc/shared"] != "data:,platform" {
+			t.Errorf("unexpected merged files %v", contents)
+		}
+		if len(cfg.Systemd.Units) != 1 || cfg.Systemd.Units[0].Name != "platform.service" {
+			t.Errorf("unexpected merged units %v", cfg.Systemd.Units)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package bootstrapinplace
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// canonicalJSON re-marshals the JSON data with sorted object keys, so that the same config
+// always results in the same bytes.
+func canonicalJSON(data []byte, pretty bool) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return marshalConfig(v, pretty)
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames it to path, so that
+// readers never see a partially written file. It returns false without writing if path already
+// has the content.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) (bool, error) {
+	if existing, err := ioutil.ReadFile(path); err == nil && bytes.Equal(existing, data) {
+		return false, nil
+	}
+
+	f, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp-")
+	if err != nil {
+		return false, err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return false, err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return false, err
+	}
+	if err := f.Close(); err != nil {
+		return false, err
+	}
+	if err := os.Chmod(f.Name(), perm); err != nil {
+		return false, err
+	}
+	return true, os.Rename(f.Name(), path)
+}
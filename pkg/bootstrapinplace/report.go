@@ -0,0 +1,126 @@
+package bootstrapinplace
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/coreos/vcontext/report"
+)
+
+// ReportFormat is the output format of the translation report.
+type ReportFormat string
+
+const (
+	// ReportText prints one line per report entry, as butane does.
+	ReportText ReportFormat = "text"
+	// ReportJSON prints the Report as JSON.
+	ReportJSON ReportFormat = "json"
+)
+
+// Report is the outcome of translating a Butane config and validating the generated Ignition
+// config, with one entry per error, warning or info message.
+type Report struct {
+	Entries []ReportEntry `json:"entries"`
+}
+
+// ReportStage is the stage of creating the Ignition config a report entry stems from.
+type ReportStage string
+
+const (
+	// StageTranslate entries stem from translating the Butane config.
+	StageTranslate ReportStage = "translate"
+	// StageMerge entries stem from merging the Ignition configs of several Butane fragments.
+	StageMerge ReportStage = "merge"
+	// StageValidate entries stem from validating the generated Ignition config.
+	StageValidate ReportStage = "validate"
+	// StageSize entries report the size of the generated Ignition config and its embedded files.
+	StageSize ReportStage = "size"
+)
+
+// ReportEntry is a single message of the translation report. Line and Column are 1-based
+// markers into the Butane config for the translate stage, and into the Ignition config
+// otherwise, or zero if the location is not known.
+type ReportEntry struct {
+	Stage ReportStage `json:"stage"`
+	// Source is the Butane fragment the entry stems from, if any.
+	Source  string `json:"source,omitempty"`
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+	// Path is the location of the entry within the config, e.g. $.storage.files.0.contents.
+	Path   string `json:"path,omitempty"`
+	Line   int64  `json:"line,omitempty"`
+	Column int64  `json:"column,omitempty"`
+}
+
+func newReport(stage ReportStage, source string, r report.Report) Report {
+	entries := make([]ReportEntry, 0, len(r.Entries))
+	for _, e := range r.Entries {
+		entry := ReportEntry{
+			Stage:   stage,
+			Source:  source,
+			Kind:    e.Kind.String(),
+			Message: e.Message,
+		}
+		if e.Context.Len() != 0 {
+			entry.Path = e.Context.String()
+		}
+		if e.Marker.StartP != nil {
+			entry.Line, entry.Column = e.Marker.Start()
+		}
+		entries = append(entries, entry)
+	}
+	return Report{Entries: entries}
+}
+
+// IsFatal returns true if the report contains errors.
+func (r Report) IsFatal() bool {
+	for _, e := range r.Entries {
+		if e.Kind == report.Error.String() {
+			return true
+		}
+	}
+	return false
+}
+
+func (r Report) String() string {
+	str := ""
+	for _, e := range r.Entries {
+		str += e.String() + "\n"
+	}
+	return str
+}
+
+func (e ReportEntry) String() string {
+	var at string
+	switch {
+	case e.Line != 0 && e.Path != "":
+		at = fmt.Sprintf(" at %s, line %d col %d", e.Path, e.Line, e.Column)
+	case e.Line != 0:
+		at = fmt.Sprintf(" at line %d col %d", e.Line, e.Column)
+	case e.Path != "":
+		at = fmt.Sprintf(" at %s", e.Path)
+	}
+	if e.Stage == StageValidate {
+		at += " in the generated Ignition config"
+	}
+	if e.Source != "" {
+		return fmt.Sprintf("%s: %s%s: %s", e.Source, e.Kind, at, e.Message)
+	}
+	return fmt.Sprintf("%s%s: %s", e.Kind, at, e.Message)
+}
+
+// Write writes the report to w in the given format.
+func (r Report) Write(w io.Writer, format ReportFormat) error {
+	switch format {
+	case ReportJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	case ReportText, "":
+		_, err := fmt.Fprintln(w, r.String())
+		return err
+	default:
+		return fmt.Errorf("unknown report format %q", format)
+	}
+}
@@ -0,0 +1,158 @@
+package bootstrapinplace
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// IncludeRules declare the asset files to embed into the Ignition config.
+type IncludeRules struct {
+	// Variant and Version are those of the generated Butane config, fcos 1.1.0 by default.
+	Variant string        `json:"variant,omitempty"`
+	Version string        `json:"version,omitempty"`
+	Rules   []IncludeRule `json:"rules"`
+}
+
+// IncludeRule embeds the asset files matching Source at Destination.
+type IncludeRule struct {
+	// Source is a glob, see path.Match, of asset files relative to the asset directory.
+	// Matching directories are included with all their files.
+	Source string `json:"source"`
+	// Destination is the absolute path of the file on the node. If it ends with a slash,
+	// it is a directory which the matching files are placed in, with their path relative to
+	// the directory of Source.
+	Destination string `json:"destination"`
+	// Mode is the file mode, e.g. 0600. It defaults to that of the asset file.
+	Mode *int `json:"mode,omitempty"`
+	// Optional rules may match no files. Otherwise this is an error.
+	Optional bool `json:"optional,omitempty"`
+}
+
+// LoadIncludeRules reads the include rules from a YAML file.
+func LoadIncludeRules(file string) (*IncludeRules, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	rules := &IncludeRules{}
+	if err := yaml.UnmarshalStrict(data, rules); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal include rules %s: %w", file, err)
+	}
+	return rules, nil
+}
+
+type butaneConfig struct {
+	Variant string `json:"variant"`
+	Version string `json:"version"`
+	Storage struct {
+		Files []butaneFile `json:"files"`
+	} `json:"storage"`
+}
+
+type butaneFile struct {
+	Path     string `json:"path"`
+	Mode     int    `json:"mode"`
+	Contents struct {
+		Local string `json:"local"`
+	} `json:"contents"`
+}
+
+// GenerateButane returns a Butane config embedding the asset files selected by the include
+// rules as local files, to be translated with assetDir as files directory. Files are sorted
+// by destination. It fails if a rule which is not optional matches no files, or if several
+// files have the same destination.
+func GenerateButane(assetDir string, rules IncludeRules) ([]byte, error) {
+	cfg := butaneConfig{Variant: rules.Variant, Version: rules.Version}
+	if len(cfg.Variant) == 0 {
+		cfg.Variant = "fcos"
+	}
+	if len(cfg.Version) == 0 {
+		cfg.Version = "1.1.0"
+	}
+
+	sources := map[string]string{}
+	for n, rule := range rules.Rules {
+		files, err := rule.files(assetDir)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d (%s): %w", n, rule.Source, err)
+		}
+		if len(files) == 0 && !rule.Optional {
+			return nil, fmt.Errorf("rule %d (%s): no asset files match", n, rule.Source)
+		}
+		for _, f := range files {
+			if source, ok := sources[f.Path]; ok {
+				return nil, fmt.Errorf("rule %d (%s): %s is also the destination of %s", n, rule.Source, f.Path, source)
+			}
+			sources[f.Path] = f.Contents.Local
+			cfg.Storage.Files = append(cfg.Storage.Files, f)
+		}
+	}
+	sort.Slice(cfg.Storage.Files, func(i, j int) bool { return cfg.Storage.Files[i].Path < cfg.Storage.Files[j].Path })
+
+	return yaml.Marshal(cfg)
+}
+
+// files returns the files the rule embeds.
+func (r IncludeRule) files(assetDir string) ([]butaneFile, error) {
+	source := path.Clean(filepath.ToSlash(r.Source))
+	if path.IsAbs(source) || source == ".." || strings.HasPrefix(source, "../") {
+		return nil, fmt.Errorf("source must be relative to the asset directory")
+	}
+	if !path.IsAbs(r.Destination) {
+		return nil, fmt.Errorf("destination %q must be absolute", r.Destination)
+	}
+	matches, err := filepath.Glob(filepath.Join(assetDir, filepath.FromSlash(source)))
+	if err != nil {
+		return nil, err
+	}
+	toDir := strings.HasSuffix(r.Destination, "/")
+	base := filepath.Join(assetDir, filepath.FromSlash(path.Dir(source)))
+
+	var files []butaneFile
+	for _, match := range matches {
+		err := filepath.Walk(match, func(p string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				return nil
+			}
+			if !info.Mode().IsRegular() {
+				return fmt.Errorf("%s is not a regular file", p)
+			}
+			local, err := filepath.Rel(assetDir, p)
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(base, p)
+			if err != nil {
+				return err
+			}
+
+			f := butaneFile{Path: r.Destination, Mode: int(info.Mode().Perm())}
+			if toDir {
+				f.Path = path.Join(r.Destination, filepath.ToSlash(rel))
+			}
+			if r.Mode != nil {
+				f.Mode = *r.Mode
+			}
+			f.Contents.Local = filepath.ToSlash(local)
+			files = append(files, f)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(files) > 1 && !toDir {
+		return nil, fmt.Errorf("%d files match, but destination %q is not a directory ending with a slash", len(files), r.Destination)
+	}
+	return files, nil
+}
@@ -0,0 +1,155 @@
+package bootstrapinplace
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerateButane(t *testing.T) {
+	dir := t.TempDir()
+	for name, mode := range map[string]os.FileMode{
+		"tls/ca.crt":            0644,
+		"tls/ca.key":            0600,
+		"tls/sub/extra.crt":     0644,
+		"auth/kubeconfig":       0600,
+		"manifests/secret.yaml": 0644,
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(name), mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mode := 0400
+
+	tests := []struct {
+		name     string
+		rules    []IncludeRule
+		expected string
+		err      string
+	}{
+		{
+			name: "directory and file destinations",
+			rules: []IncludeRule{
+				{Source: "tls/*", Destination: "/etc/kubernetes/bootstrap-secrets/"},
+				{Source: "auth/kubeconfig", Destination: "/etc/kubernetes/kubeconfig", Mode: &mode},
+				{Source: "missing/*", Destination: "/etc/missing/", Optional: true},
+			},
+			expected: `storage:
+  files:
+  - contents:
+      local: tls/ca.crt
+    mode: 420
+    path: /etc/kubernetes/bootstrap-secrets/ca.crt
+  - contents:
+      local: tls/ca.key
+    mode: 384
+    path: /etc/kubernetes/bootstrap-secrets/ca.key
+  - contents:
+      local: tls/sub/extra.crt
+    mode: 420
+    path: /etc/kubernetes/bootstrap-secrets/sub/extra.crt
+  - contents:
+      local: auth/kubeconfig
+    mode: 256
+    path: /etc/kubernetes/kubeconfig
+variant: fcos
+version: 1.1.0
+`,
+		},
+		{
+			name:  "missing files",
+			rules: []IncludeRule{{Source: "missing/*", Destination: "/etc/missing/"}},
+			err:   "rule 0 (missing/*): no asset files match",
+		},
+		{
+			name: "duplicate destination",
+			rules: []IncludeRule{
+				{Source: "tls/ca.crt", Destination: "/etc/ca"},
+				{Source: "tls/ca.key", Destination: "/etc/ca"},
+			},
+			err: "rule 1 (tls/ca.key): /etc/ca is also the destination of tls/ca.crt",
+		},
+		{
+			name:  "several files to a file destination",
+			rules: []IncludeRule{{Source: "tls/*", Destination: "/etc/ca"}},
+			err:   `destination "/etc/ca" is not a directory`,
+		},
+		{
+			name:  "source outside the asset dir",
+			rules: []IncludeRule{{Source: "../etc/passwd", Destination: "/etc/passwd"}},
+			err:   "source must be relative to the asset directory",
+		},
+		{
+			name:  "relative destination",
+			rules: []IncludeRule{{Source: "auth/kubeconfig", Destination: "kubeconfig"}},
+			err:   `destination "kubeconfig" must be absolute`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := GenerateButane(dir, IncludeRules{Rules: tt.rules})
+			if len(tt.err) > 0 {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("expected error %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(data) != tt.expected {
+				t.Errorf("expected:\n%s\ngot:\n%s", tt.expected, data)
+			}
+		})
+	}
+}
+
+func TestCreateWithIncludeRules(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "tls"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "tls", "ca.crt"), []byte("cert"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rulesPath := filepath.Join(dir, "rules.yaml")
+	rules := "rules:\n- source: tls/*\n  destination: /etc/kubernetes/tls/\n"
+	if err := os.WriteFile(rulesPath, []byte(rules), 0644); err != nil {
+		t.Fatal(err)
+	}
+	butanePath := filepath.Join(dir, "equivalent.bu")
+	butane := "variant: fcos\nversion: 1.1.0\nstorage:\n  files:\n  - path: /etc/kubernetes/tls/ca.crt\n    mode: 0644\n    contents:\n      local: tls/ca.crt\n"
+	if err := os.WriteFile(butanePath, []byte(butane), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	create := func(config BootstrapInPlaceConfig) []byte {
+		config.AssetDir = dir
+		config.IgnitionPath = filepath.Join(t.TempDir(), "out.ign")
+		config.Out = &bytes.Buffer{}
+		bip, err := NewBootstrapInPlaceCommand(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := bip.Create(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		data, err := os.ReadFile(config.IgnitionPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+
+	generated := create(BootstrapInPlaceConfig{IncludeRules: rulesPath})
+	expected := create(BootstrapInPlaceConfig{Inputs: []string{butanePath}})
+	if !bytes.Equal(generated, expected) {
+		t.Errorf("expected the same ignition as the equivalent fcc\n%s\ngot:\n%s", expected, generated)
+	}
+}
@@ -0,0 +1,145 @@
+package bootstrapinplace
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/coreos/vcontext/report"
+	"github.com/vincent-petithory/dataurl"
+)
+
+// SizeError is returned if the generated Ignition config exceeds the size budget. Report
+// holds the size contribution of the embedded files.
+type SizeError struct {
+	Size   int
+	Budget int
+	Report Report
+}
+
+func (e *SizeError) Error() string {
+	return fmt.Sprintf("generated Ignition config is %d bytes, exceeding the budget of %d bytes", e.Size, e.Budget)
+}
+
+// compressFiles gzip-compresses the contents of the files which are embedded as data URLs of
+// more than threshold bytes, if this makes them smaller. Files which are already compressed or
+// whose contents are verified by hash are left alone.
+func compressFiles(data []byte, threshold int, pretty bool) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var cfg map[string]interface{}
+	if err := dec.Decode(&cfg); err != nil {
+		return nil, err
+	}
+
+	storage, _ := cfg["storage"].(map[string]interface{})
+	files, _ := storage["files"].([]interface{})
+	for _, f := range files {
+		file, _ := f.(map[string]interface{})
+		contents, _ := file["contents"].(map[string]interface{})
+		source, _ := contents["source"].(string)
+		compression, _ := contents["compression"].(string)
+		verification, _ := contents["verification"].(map[string]interface{})
+		if len(source) == 0 || len(compression) > 0 || verification["hash"] != nil {
+			continue
+		}
+		du, err := dataurl.DecodeString(source)
+		if err != nil || len(du.Data) <= threshold {
+			// remote sources are fetched as they are
+			continue
+		}
+
+		buf := &bytes.Buffer{}
+		w, err := gzip.NewWriterLevel(buf, gzip.BestCompression)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(du.Data); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		compressed := "data:;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
+		if len(compressed) >= len(source) {
+			continue
+		}
+		contents["source"] = compressed
+		contents["compression"] = "gzip"
+	}
+	return marshalConfig(cfg, pretty)
+}
+
+// fileSizes returns one info entry per embedded file with the bytes its contents add to the
+// Ignition config, largest first, and one with the size of the whole config. If budget is
+// positive and the config exceeds it, the latter is an error.
+func fileSizes(data []byte, budget int) (Report, error) {
+	var cfg struct {
+		Storage struct {
+			Files []struct {
+				Path     string `json:"path"`
+				Contents struct {
+					Source      *string `json:"source"`
+					Compression *string `json:"compression"`
+				} `json:"contents"`
+			} `json:"files"`
+		} `json:"storage"`
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return Report{}, err
+	}
+
+	type fileSize struct {
+		index int
+		path  string
+		size  int
+		gzip  bool
+	}
+	var sizes []fileSize
+	for n, f := range cfg.Storage.Files {
+		if f.Contents.Source == nil {
+			continue
+		}
+		sizes = append(sizes, fileSize{
+			index: n,
+			path:  f.Path,
+			size:  len(*f.Contents.Source),
+			gzip:  f.Contents.Compression != nil && *f.Contents.Compression == "gzip",
+		})
+	}
+	sort.SliceStable(sizes, func(i, j int) bool { return sizes[i].size > sizes[j].size })
+
+	var r Report
+	for _, s := range sizes {
+		msg := fmt.Sprintf("%s embeds %d bytes", s.path, s.size)
+		if s.gzip {
+			msg += ", gzip-compressed"
+		}
+		r.Entries = append(r.Entries, ReportEntry{
+			Stage:   StageSize,
+			Kind:    report.Info.String(),
+			Message: msg,
+			Path:    fmt.Sprintf("$.storage.files.%d.contents.source", s.index),
+		})
+	}
+
+	total := ReportEntry{Stage: StageSize, Kind: report.Info.String()}
+	switch {
+	case budget > 0 && len(data) > budget:
+		total.Kind = report.Error.String()
+		total.Message = fmt.Sprintf("config is %d bytes, exceeding the budget of %d bytes", len(data), budget)
+	case budget > 0:
+		total.Message = fmt.Sprintf("config is %d bytes of a budget of %d bytes", len(data), budget)
+	default:
+		total.Message = fmt.Sprintf("config is %d bytes", len(data))
+	}
+	r.Entries = append(r.Entries, total)
+
+	if budget > 0 && len(data) > budget {
+		return r, &SizeError{Size: len(data), Budget: budget, Report: r}
+	}
+	return r, nil
+}
@@ -0,0 +1,113 @@
+package bootstrapinplace
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vincent-petithory/dataurl"
+)
+
+func TestCreateCompressesAndBudgetsFiles(t *testing.T) {
+	dir := t.TempDir()
+	large := strings.Repeat("compressible ", 1000)
+	if err := os.WriteFile(filepath.Join(dir, "large"), []byte(large), 0644); err != nil {
+		t.Fatal(err)
+	}
+	input := filepath.Join(dir, "input.bu")
+	config := "variant: fcos\nversion: 1.1.0\nstorage:\n  files:\n  - path: /etc/large\n    contents:\n      local: large\n  - path: /etc/small\n    contents:\n      inline: " + strings.Repeat("s", 90) + "\n"
+	if err := os.WriteFile(input, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	create := func(threshold, budget int) (string, string, error) {
+		out := &bytes.Buffer{}
+		ignitionPath := filepath.Join(t.TempDir(), "out.ign")
+		bip, err := NewBootstrapInPlaceCommand(BootstrapInPlaceConfig{
+			AssetDir:          dir,
+			IgnitionPath:      ignitionPath,
+			Inputs:            []string{input},
+			CompressThreshold: threshold,
+			SizeBudget:        budget,
+			Out:               out,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = bip.Create()
+		return ignitionPath, out.String(), err
+	}
+
+	_, out, err := create(0, 200)
+	var sizeErr *SizeError
+	if !errors.As(err, &sizeErr) || sizeErr.Budget != 200 {
+		t.Fatalf("expected SizeError for the uncompressed config, got %v: %s", err, out)
+	}
+	if !strings.Contains(out, "info at $.storage.files.0.contents.source: /etc/large embeds") || !strings.Contains(out, "exceeding the budget of 200 bytes") {
+		t.Errorf("expected file sizes and the exceeded budget to be reported, got %q", out)
+	}
+
+	ignitionPath, out, err := create(100, 1000)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out, "/etc/large embeds") || !strings.Contains(out, ", gzip-compressed") || !strings.Contains(out, "of a budget of 1000 bytes") {
+		t.Errorf("expected the compressed file size to be reported, got %q", out)
+	}
+	data, err := os.ReadFile(ignitionPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cfg struct {
+		Storage struct {
+			Files []struct {
+				Path     string `json:"path"`
+				Contents struct {
+					Source      string `json:"source"`
+					Compression string `json:"compression"`
+				} `json:"contents"`
+			} `json:"files"`
+		} `json:"storage"`
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range cfg.Storage.Files {
+		du, err := dataurl.DecodeString(f.Contents.Source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch f.Path {
+		case "/etc/large":
+			if f.Contents.Compression != "gzip" {
+				t.Fatalf("expected %s to be gzip-compressed", f.Path)
+			}
+			r, err := gzip.NewReader(bytes.NewReader(du.Data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			content, err := ioutil.ReadAll(r)
+			if err != nil || string(content) != large {
+				t.Errorf("expected %s to decompress to its content, got %v", f.Path, err)
+			}
+		case "/etc/small":
+			if f.Contents.Compression != "" || len(du.Data) != 90 {
+				t.Errorf("expected %s below the threshold not to be compressed", f.Path)
+			}
+		}
+	}
+
+	// the budget applies to the file as written, including its trailing newline
+	if _, out, err := create(100, len(data)); err != nil {
+		t.Errorf("expected a budget of the written size to be met, got %v: %s", err, out)
+	}
+	if _, _, err := create(100, len(data)-1); !errors.As(err, &sizeErr) {
+		t.Errorf("expected SizeError for a budget below the written size, got %v", err)
+	}
+}
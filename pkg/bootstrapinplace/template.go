@@ -0,0 +1,91 @@
+package bootstrapinplace
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/openshift/installer/pkg/types"
+	"sigs.k8s.io/yaml"
+
+	"github.com/openshift/cluster-bootstrap/pkg/installconfig"
+)
+
+// TemplateError is returned if a Butane config cannot be rendered as template.
+type TemplateError struct {
+	Path string
+	Err  error
+}
+
+func (e *TemplateError) Error() string {
+	return fmt.Sprintf("failed to render template %s: %v", e.Path, e.Err)
+}
+
+func (e *TemplateError) Unwrap() error {
+	return e.Err
+}
+
+// TemplateData is the data Butane configs are rendered with in template mode.
+type TemplateData struct {
+	// InstallConfig is the parsed install config.
+	InstallConfig *types.InstallConfig
+	// InstallConfigValues is the install config as generic values, for fields the
+	// InstallConfig type does not know, e.g. {{ .InstallConfigValues.featureSet }}.
+	InstallConfigValues map[string]interface{}
+}
+
+// loadTemplateData returns the template data of the install config of the cluster-config
+// ConfigMap manifest file.
+func loadTemplateData(clusterConfig string) (*TemplateData, error) {
+	installConfig, err := installconfig.Load(clusterConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get install config from %s: %w", clusterConfig, err)
+	}
+	data, err := installconfig.Data(clusterConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get install config from %s: %w", clusterConfig, err)
+	}
+	values := map[string]interface{}{}
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal install config %w", err)
+	}
+	return &TemplateData{InstallConfig: installConfig, InstallConfigValues: values}, nil
+}
+
+// templateFuncs returns the template helpers, modelled on those of library-go's assets package.
+// Files are loaded relative to assetDir and cannot escape it.
+func templateFuncs(assetDir string) template.FuncMap {
+	return template.FuncMap{
+		"base64": func(v string) string {
+			return base64.StdEncoding.EncodeToString([]byte(v))
+		},
+		"indent": func(indention int, v string) string {
+			newline := "\n" + strings.Repeat(" ", indention)
+			return strings.Replace(v, "\n", newline, -1)
+		},
+		"load": func(name string) (string, error) {
+			data, err := ioutil.ReadFile(filepath.Join(assetDir, filepath.Clean("/"+name)))
+			if err != nil {
+				return "", err
+			}
+			return string(data), nil
+		},
+	}
+}
+
+// renderTemplate renders the Butane config as Go template. Missing keys are errors.
+func renderTemplate(name string, data []byte, assetDir string, templateData *TemplateData) ([]byte, error) {
+	tmpl, err := template.New(name).Funcs(templateFuncs(assetDir)).Option("missingkey=error").Parse(string(data))
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, templateData); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,126 @@
+package bootstrapinplace
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testClusterConfig = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: cluster-config-v1
+  namespace: kube-system
+data:
+  install-config: |
+    apiVersion: v1
+    metadata:
+      name: sno
+    baseDomain: example.com
+    featureSet: TechPreviewNoUpgrade
+`
+
+func TestCreateTemplate(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		expected    []string
+		expectedErr string
+	}{
+		{
+			name: "install config values and helpers",
+			input: `variant: fcos
+version: 1.1.0
+storage:
+  files:
+  - path: /etc/cluster
+    contents:
+      inline: {{ .InstallConfig.ObjectMeta.Name }}.{{ .InstallConfig.BaseDomain }}
+  - path: /etc/feature-set
+    contents:
+      inline: {{ .InstallConfigValues.featureSet }}
+  - path: /etc/ca.crt
+    contents:
+      source: data:;base64,{{ load "tls/ca.crt" | base64 }}
+  - path: /etc/multiline
+    contents:
+      inline: |
+        {{ load "tls/ca.crt" | indent 8 }}
+`,
+			expected: []string{
+				`"source": "data:,sno.example.com"`,
+				`"source": "data:,TechPreviewNoUpgrade"`,
+				`"source": "data:;base64,Y2VydApkYXRhCg=="`,
+				`"source": "data:,cert%0Adata%0A"`,
+			},
+		},
+		{
+			name:        "missing key",
+			input:       "variant: fcos\nversion: 1.1.0\nstorage:\n  files:\n  - path: /etc/{{ .InstallConfigValues.missing }}\n",
+			expectedErr: "missing",
+		},
+		{
+			name:        "load outside of the asset directory",
+			input:       "variant: fcos\nversion: 1.1.0\nstorage:\n  files:\n  - path: /etc/foo\n    contents:\n      inline: {{ load \"../secret\" }}\n",
+			expectedErr: "no such file or directory",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := t.TempDir()
+			assetDir := filepath.Join(root, "assets")
+			for path, content := range map[string]string{
+				"manifests/cluster-config.yaml": testClusterConfig,
+				"tls/ca.crt":                    "cert\ndata\n",
+				"input.bu":                      test.input,
+			} {
+				if err := os.MkdirAll(filepath.Dir(filepath.Join(assetDir, path)), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(filepath.Join(assetDir, path), []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := os.WriteFile(filepath.Join(root, "secret"), []byte("secret"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			ignitionPath := filepath.Join(root, "out.ign")
+			bip, err := NewBootstrapInPlaceCommand(BootstrapInPlaceConfig{
+				AssetDir:     assetDir,
+				IgnitionPath: ignitionPath,
+				Inputs:       []string{filepath.Join(assetDir, "input.bu")},
+				Pretty:       true,
+				Template:     true,
+				Out:          io.Discard,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = bip.Create()
+			if test.expectedErr != "" {
+				var templateErr *TemplateError
+				if !errors.As(err, &templateErr) || !strings.Contains(err.Error(), test.expectedErr) {
+					t.Fatalf("expected TemplateError containing %q, got %v", test.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			data, err := os.ReadFile(ignitionPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, expected := range test.expected {
+				if !strings.Contains(string(data), expected) {
+					t.Errorf("expected output to contain %s, got %s", expected, data)
+				}
+			}
+		})
+	}
+}
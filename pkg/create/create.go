@@ -0,0 +1,521 @@
+// Package create creates the manifests of the asset directory in the cluster.
+//
+// It is derived from github.com/openshift/library-go/pkg/assets/create, and additionally
+// records the outcome of every single manifest in a Tracker. Manifest files may contain
+// several YAML documents and v1 List objects, whose objects are identified as <path>#<index>.
+package create
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	kyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+
+	"github.com/openshift/library-go/pkg/assets"
+	"github.com/openshift/library-go/pkg/client/openshiftrestmapper"
+)
+
+// CreateOptions allow to specify additional create options.
+type CreateOptions struct {
+	// Filters allows to filter which files we will read from disk.
+	// Multiple filters can be specified, in that case only files matching all filters will be returned.
+	Filters []assets.FileInfoPredicate
+
+	// Verbose if true will print out extra messages for debugging
+	Verbose bool
+
+	// StdErr allows to override the standard error output for printing verbose messages.
+	// If not set, os.StdErr is used.
+	StdErr io.Writer
+
+	// PathFilters allows to skip manifest files by their path relative to the manifests directory,
+	// before they are decoded. Only files matching all filters are loaded.
+	PathFilters []PathPredicate
+
+	// ManifestFilters allows to skip loaded manifests, e.g. based on their annotations.
+	// Only manifests matching all filters are created.
+	ManifestFilters []ManifestPredicate
+
+	// OverrideDirs are additional manifest directories layered on top of the manifests directory,
+	// in increasing order of precedence. If several layers define the same object, i.e. the same
+	// group, kind, namespace and name, only the manifest of the highest layer is created and the
+	// others are skipped. Manifests of override directories are identified by their full path.
+	OverrideDirs []string
+
+	// Tracker if set records the outcome of every manifest.
+	Tracker *Tracker
+
+	// Strict if true stops retrying as soon as a creation attempt fails while all kinds are
+	// known to the discovery. Unknown kinds are still retried, as their CRDs may not be
+	// established yet.
+	Strict bool
+}
+
+// NotCreatedError is returned by EnsureManifestsCreated if not all manifests were created, because
+// the context was done or, with CreateOptions.Strict, a creation attempt failed. Errors loading the
+// manifests or building the clients are returned as they are.
+type NotCreatedError struct {
+	Err error
+}
+
+func (e *NotCreatedError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *NotCreatedError) Unwrap() error {
+	return e.Err
+}
+
+// EnsureManifestsCreated ensures that all resource manifests from the specified directory are created.
+// This function will try to create remaining resources in the manifest list after error is occurred.
+// This function will keep retrying creation until no errors are reported or the timeout is hit,
+// unless options.Strict is set.
+// Pass the context to indicate how much time you are willing to wait until all resources are created.
+func EnsureManifestsCreated(ctx context.Context, manifestDir string, restConfig *rest.Config, options CreateOptions) error {
+	client, dc, err := newClientsFn(restConfig)
+	if err != nil {
+		return err
+	}
+
+	if options.StdErr == nil {
+		options.StdErr = os.Stderr
+	}
+
+	manifests, err := load(manifestDir, options)
+	if err != nil {
+		return err
+	}
+
+	// Default QPS in client (when not specified) is 5 requests/per second
+	// This specifies the interval between "create-all-resources", no need to make this configurable.
+	interval := 200 * time.Millisecond
+
+	// Retry creation until no errors are returned or the timeout is hit.
+	var (
+		lastCreateError      error
+		retryCount           int
+		mapper               meta.RESTMapper
+		needDiscoveryRefresh bool = true
+	)
+	err = wait.PollImmediateUntil(interval, func() (bool, error) {
+		retryCount++
+		// If we get rest mapper error, we need to pull updated discovery info from API server
+		if needDiscoveryRefresh {
+			mapper, err = fetchLatestDiscoveryInfoFn(dc)
+			if err != nil {
+				if options.Verbose {
+					fmt.Fprintf(options.StdErr, "[#%d] failed to fetch discovery: %s\n", retryCount, err)
+				}
+				return false, nil
+			}
+		}
+		err, needDiscoveryRefresh = create(ctx, manifests, client, mapper, options)
+		if err == nil {
+			lastCreateError = nil
+			return true, nil
+		}
+		if ctx.Err() == nil || lastCreateError == nil {
+			lastCreateError = err
+		}
+		if options.Verbose {
+			fmt.Fprintf(options.StdErr, "[#%d] %s\n", retryCount, err)
+		}
+		if options.Strict && !needDiscoveryRefresh {
+			return false, err
+		}
+		return false, nil
+	}, ctx.Done())
+
+	// Return the last observed set of errors from the create process instead of timeout error.
+	if lastCreateError != nil {
+		return &NotCreatedError{Err: lastCreateError}
+	}
+	if err != nil {
+		return &NotCreatedError{Err: err}
+	}
+	return nil
+}
+
+// allow to override in unit test
+var newClientsFn = newClients
+
+func newClients(config *rest.Config) (dynamic.Interface, *discovery.DiscoveryClient, error) {
+	client, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// TODO: We can use cacheddiscovery.NewMemCacheClient(dc) and then call .Invalidate() instead of fetchLatestDiscoveryInfo.
+	// It will require more work in unit test though.
+	// discovery is very bursty and we have lots and lots of groups
+	discoveryConfig := rest.CopyConfig(config)
+	discoveryConfig.Burst = 200
+	discoveryConfig.QPS = 50
+	dc, err := discovery.NewDiscoveryClientForConfig(discoveryConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return client, dc, nil
+}
+
+// allow to override in unit test
+var fetchLatestDiscoveryInfoFn = fetchLatestDiscoveryInfo
+
+func fetchLatestDiscoveryInfo(dc *discovery.DiscoveryClient) (meta.RESTMapper, error) {
+	gr, err := restmapper.GetAPIGroupResources(dc)
+	if err != nil {
+		return nil, err
+	}
+	defaultRESTMapper := restmapper.NewDiscoveryRESTMapper(gr)
+	wrappedRESTMapper := openshiftrestmapper.NewOpenShiftHardcodedRESTMapper(defaultRESTMapper)
+	return wrappedRESTMapper, nil
+}
+
+// create will attempt to create all manifests provided using dynamic client.
+// It will mutate the manifests argument in case the create succeeded for given manifest. When all manifests are successfully created the resulting
+// manifests argument should be empty.
+func create(ctx context.Context, manifests map[string]*unstructured.Unstructured, client dynamic.Interface, mapper meta.RESTMapper, options CreateOptions) (error, bool) {
+	sortedManifestPaths := []string{}
+	for key := range manifests {
+		sortedManifestPaths = append(sortedManifestPaths, key)
+	}
+	sort.Strings(sortedManifestPaths)
+
+	// Record all errors for the given manifest path (so when we report errors, users can see what manifest failed).
+	errs := map[string]error{}
+
+	// In case we fail to find a rest-mapping for the resource, force to fetch the updated discovery on next run.
+	reloadDiscovery := false
+
+	for _, path := range sortedManifestPaths {
+		select {
+		case <-ctx.Done():
+			return ctx.Err(), false
+		default:
+		}
+
+		options.Tracker.attempt(path)
+
+		gvk := manifests[path].GetObjectKind().GroupVersionKind()
+		mappings, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err != nil {
+			errs[path] = fmt.Errorf("unable to get REST mapping for %q: %v", path, err)
+			options.Tracker.failed(path, errs[path])
+			reloadDiscovery = true
+			continue
+		}
+
+		var resource dynamic.ResourceInterface
+		if mappings.Scope.Name() == meta.RESTScopeNameRoot {
+			resource = client.Resource(mappings.Resource)
+		} else {
+			resource = client.Resource(mappings.Resource).Namespace(manifests[path].GetNamespace())
+		}
+		resourceString := mappings.Resource.Resource + "." + mappings.Resource.Version + "." + mappings.Resource.Group + "/" + manifests[path].GetName() + " -n " + manifests[path].GetNamespace()
+
+		state := ManifestAlreadyExists
+		incluster, err := resource.Get(ctx, manifests[path].GetName(), metav1.GetOptions{})
+		switch {
+		case err == nil:
+			if options.Verbose {
+				fmt.Fprintf(options.StdErr, "Skipped %q %s as it already exists\n", path, resourceString)
+			}
+			// fall through as if it was just created
+		case !kerrors.IsNotFound(err):
+			if options.Verbose {
+				fmt.Fprintf(options.StdErr, "Failed to get %q %s: %v\n", path, resourceString, err)
+			}
+			errs[path] = fmt.Errorf("failed to get %s: %v", resourceString, err)
+			options.Tracker.failed(path, errs[path])
+			continue
+		case kerrors.IsNotFound(err):
+			incluster, err = resource.Create(ctx, manifests[path], metav1.CreateOptions{})
+			if err == nil {
+				state = ManifestCreated
+				if options.Verbose {
+					fmt.Fprintf(options.StdErr, "Created %q %s\n", path, resourceString)
+				}
+			}
+			if kerrors.IsAlreadyExists(err) {
+				if options.Verbose {
+					fmt.Fprintf(options.StdErr, "Skipped creating %q %s as it already exists\n", path, resourceString)
+				}
+				// fall through as if it was just created
+			} else if err != nil {
+				if options.Verbose {
+					fmt.Fprintf(options.StdErr, "Failed to create %q %s: %v\n", path, resourceString, err)
+				}
+				errs[path] = fmt.Errorf("failed to create %s: %v", resourceString, err)
+				options.Tracker.failed(path, errs[path])
+				continue
+			}
+		}
+
+		if _, ok := manifests[path].Object["status"]; ok {
+			_, found := incluster.Object["status"]
+			if !found {
+				incluster.Object["status"] = manifests[path].Object["status"]
+				incluster, err = resource.UpdateStatus(ctx, incluster, metav1.UpdateOptions{})
+				if err != nil && !kerrors.IsNotFound(err) {
+					if options.Verbose {
+						fmt.Fprintf(options.StdErr, "Failed to update status for the %q %s: %v\n", path, resourceString, err)
+					}
+					errs[path] = fmt.Errorf("failed to update status for %s: %v", resourceString, err)
+					options.Tracker.failed(path, errs[path])
+					continue
+				}
+				if err == nil {
+					state = ManifestStatusUpdated
+					if options.Verbose {
+						fmt.Fprintf(options.StdErr, "Updated status for %q %s\n", path, resourceString)
+					}
+				}
+			}
+		}
+		// Creation succeeded lets remove the manifest from the list to avoid creating it second time
+		options.Tracker.succeeded(path, state)
+		delete(manifests, path)
+	}
+
+	return formatErrors("failed to create some manifests", errs), reloadDiscovery
+}
+
+func formatErrors(prefix string, errors map[string]error) error {
+	if len(errors) == 0 {
+		return nil
+	}
+	aggregatedErrMessages := []string{}
+	keys := []string{}
+	for key := range errors {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		aggregatedErrMessages = append(aggregatedErrMessages, fmt.Sprintf("%q: %v", k, errors[k]))
+	}
+	return fmt.Errorf("%s:\n%s", prefix, strings.Join(aggregatedErrMessages, "\n"))
+}
+
+func load(assetsDir string, options CreateOptions) (map[string]*unstructured.Unstructured, error) {
+	manifests := map[string]*unstructured.Unstructured{}
+	layers := map[string]int{}
+	// the file path relative to its manifest directory, as matched by the filters
+	files := map[string]string{}
+	errs := map[string]error{}
+	for layer, dir := range append([]string{assetsDir}, options.OverrideDirs...) {
+		manifestsBytesMap, err := assets.LoadFilesRecursively(dir, options.Filters...)
+		if err != nil {
+			return nil, err
+		}
+
+		for relPath, manifestBytes := range manifestsBytesMap {
+			manifestPath := relPath
+			if layer > 0 {
+				manifestPath = filepath.Join(dir, relPath)
+			}
+			if include, reason := filterPath(relPath, options.PathFilters); !include {
+				if options.Verbose {
+					fmt.Fprintf(options.StdErr, "Skipped %q as %s\n", manifestPath, reason)
+				}
+				options.Tracker.skip(manifestPath, nil, reason)
+				continue
+			}
+			objects, err := decodeManifests(manifestPath, manifestBytes)
+			if err != nil {
+				errs[manifestPath] = err
+				continue
+			}
+			for objectPath, manifestUnstructured := range objects {
+				manifests[objectPath] = manifestUnstructured
+				layers[objectPath] = layer
+				files[objectPath] = relPath
+			}
+		}
+	}
+	if err := formatErrors("failed to load some manifests", errs); err != nil {
+		return nil, err
+	}
+
+	for path, manifest := range manifests {
+		if include, reason := filter(files[path], manifest, options.ManifestFilters); !include {
+			if options.Verbose {
+				fmt.Fprintf(options.StdErr, "Skipped %q as %s\n", path, reason)
+			}
+			options.Tracker.skip(path, manifest, reason)
+			delete(manifests, path)
+		}
+	}
+
+	// Resolve overrides only after filtering, so that a manifest not applying to this cluster
+	// does not hide the one of a lower layer.
+	for path, by := range overrides(manifests, layers) {
+		reason := fmt.Sprintf("it is overridden by %q", by)
+		fmt.Fprintf(options.StdErr, "Skipped %q as %s\n", path, reason)
+		options.Tracker.skip(path, manifests[path], reason)
+		delete(manifests, path)
+	}
+	for id, paths := range duplicates(manifests) {
+		fmt.Fprintf(options.StdErr, "Warning: %s is defined by several manifests of the same layer, all of them are created: %s\n", id, strings.Join(paths, ", "))
+	}
+
+	for path, manifest := range manifests {
+		options.Tracker.add(path, manifest)
+	}
+	return manifests, nil
+}
+
+// decodeManifests decodes all objects of the manifest file, splitting YAML documents and
+// expanding the items of v1 List objects. Objects are keyed by the manifest path if the file
+// consists of a single object only, and by <path>#<index> otherwise, with the index zero-padded
+// to the same width.
+func decodeManifests(manifestPath string, manifestBytes []byte) (map[string]*unstructured.Unstructured, error) {
+	var objects []*unstructured.Unstructured
+	isList := false
+	reader := kyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(manifestBytes)))
+	for doc := 0; ; doc++ {
+		docBytes, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to read YAML document %d of asset %q: %v", doc, manifestPath, err)
+		}
+		manifestJSON, err := yaml.YAMLToJSON(docBytes)
+		if err != nil {
+			return nil, fmt.Errorf("unable to convert document %d of asset %q from YAML to JSON: %v", doc, manifestPath, err)
+		}
+		// skip documents which are empty or only contain comments
+		if trimmed := bytes.TrimSpace(manifestJSON); len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null")) {
+			continue
+		}
+		manifestObj, err := runtime.Decode(unstructured.UnstructuredJSONScheme, manifestJSON)
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode document %d of asset %q: %v", doc, manifestPath, err)
+		}
+		switch obj := manifestObj.(type) {
+		case *unstructured.Unstructured:
+			objects = append(objects, obj)
+		case *unstructured.UnstructuredList:
+			if gvk := obj.GroupVersionKind(); gvk.Group != "" || gvk.Version != "v1" {
+				return nil, fmt.Errorf("unable to decode document %d of asset %q: only v1 lists are supported, got %s", doc, manifestPath, gvk)
+			}
+			isList = true
+			for i := range obj.Items {
+				objects = append(objects, &obj.Items[i])
+			}
+		default:
+			return nil, fmt.Errorf("unable to convert document %d of asset %q to unstructured", doc, manifestPath)
+		}
+	}
+
+	if len(objects) == 0 && !isList {
+		return nil, fmt.Errorf("unable to decode asset %q: no objects found", manifestPath)
+	}
+	if len(objects) == 1 && !isList {
+		return map[string]*unstructured.Unstructured{manifestPath: objects[0]}, nil
+	}
+	// zero-pad the index, so that the keys sort in the order of the objects
+	width := len(strconv.Itoa(len(objects) - 1))
+	result := make(map[string]*unstructured.Unstructured, len(objects))
+	for i, obj := range objects {
+		result[fmt.Sprintf("%s#%0*d", manifestPath, width, i)] = obj
+	}
+	return result, nil
+}
+
+// overrides returns the manifests overridden by a manifest of a higher layer for the same
+// object, mapped to the path of the overriding manifest. Manifests of the same layer never
+// override each other.
+func overrides(manifests map[string]*unstructured.Unstructured, layers map[string]int) map[string]string {
+	winners := map[string]string{}
+	for path, manifest := range manifests {
+		if len(manifest.GetName()) == 0 {
+			// objects named by the server cannot be overridden
+			continue
+		}
+		id := objectID(manifest)
+		if winner, ok := winners[id]; !ok || layers[path] > layers[winner] || (layers[path] == layers[winner] && path > winner) {
+			winners[id] = path
+		}
+	}
+
+	overridden := map[string]string{}
+	for path, manifest := range manifests {
+		if len(manifest.GetName()) == 0 {
+			continue
+		}
+		if winner := winners[objectID(manifest)]; layers[path] < layers[winner] {
+			overridden[path] = winner
+		}
+	}
+	return overridden
+}
+
+// duplicates returns the sorted paths of the manifests defining the same object, by object.
+// After overrides are resolved, these are manifests of the same layer.
+func duplicates(manifests map[string]*unstructured.Unstructured) map[string][]string {
+	paths := map[string][]string{}
+	for path, manifest := range manifests {
+		if len(manifest.GetName()) == 0 {
+			continue
+		}
+		id := objectID(manifest)
+		paths[id] = append(paths[id], path)
+	}
+	for id := range paths {
+		if len(paths[id]) < 2 {
+			delete(paths, id)
+			continue
+		}
+		sort.Strings(paths[id])
+	}
+	return paths
+}
+
+// objectID identifies the object of a manifest regardless of its API version.
+func objectID(manifest *unstructured.Unstructured) string {
+	gk := manifest.GroupVersionKind().GroupKind()
+	return fmt.Sprintf("%s/%s/%s", gk.String(), manifest.GetNamespace(), manifest.GetName())
+}
+
+// filterPath returns false and the reason if any of the predicates excludes the path.
+func filterPath(path string, predicates []PathPredicate) (bool, string) {
+	for _, p := range predicates {
+		if include, reason := p(path); !include {
+			return false, reason
+		}
+	}
+	return true, ""
+}
+
+// filter returns false and the reason if any of the predicates excludes the manifest.
+func filter(path string, manifest *unstructured.Unstructured, predicates []ManifestPredicate) (bool, string) {
+	for _, p := range predicates {
+		if include, reason := p(path, manifest); !include {
+			return false, reason
+		}
+	}
+	return true, ""
+}
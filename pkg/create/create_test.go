@@ -0,0 +1,391 @@
+package create
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+	ktesting "k8s.io/client-go/testing"
+)
+
+var testResources = []*restmapper.APIGroupResources{
+	{
+		Group: metav1.APIGroup{
+			Name:             "",
+			Versions:         []metav1.GroupVersionForDiscovery{{Version: "v1"}},
+			PreferredVersion: metav1.GroupVersionForDiscovery{Version: "v1"},
+		},
+		VersionedResources: map[string][]metav1.APIResource{
+			"v1": {
+				{Name: "configmaps", Namespaced: true, Kind: "ConfigMap"},
+				{Name: "namespaces", Namespaced: false, Kind: "Namespace"},
+			},
+		},
+	},
+}
+
+func testManifest(apiVersion, kind, namespace, name string) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{}
+	u.SetAPIVersion(apiVersion)
+	u.SetKind(kind)
+	u.SetNamespace(namespace)
+	u.SetName(name)
+	return u
+}
+
+func TestCreateTracksOutcome(t *testing.T) {
+	existing := testManifest("v1", "Namespace", "", "existing")
+	manifests := map[string]*unstructured.Unstructured{
+		"0000_namespace.yaml": testManifest("v1", "Namespace", "", "existing"),
+		"0001_configmap.yaml": testManifest("v1", "ConfigMap", "existing", "cm"),
+		"0002_unknown.yaml":   testManifest("example.com/v1", "Unknown", "", "unknown"),
+		"0003_forbidden.yaml": testManifest("v1", "ConfigMap", "existing", "forbidden"),
+		"0004_with-status.yaml": func() *unstructured.Unstructured {
+			u := testManifest("v1", "Namespace", "", "with-status")
+			u.Object["status"] = map[string]interface{}{"phase": "Active"}
+			return u
+		}(),
+	}
+
+	// the status of an existing object without status is updated from the manifest
+	existingWithoutStatus := testManifest("v1", "Namespace", "", "with-status")
+	client := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), existing, existingWithoutStatus)
+	client.PrependReactor("create", "configmaps", func(action ktesting.Action) (bool, runtime.Object, error) {
+		if action.(ktesting.CreateAction).GetObject().(*unstructured.Unstructured).GetName() == "forbidden" {
+			return true, nil, errors.New("forbidden")
+		}
+		return false, nil, nil
+	})
+	mapper := meta.RESTMapper(restmapper.NewDiscoveryRESTMapper(testResources))
+
+	tracker := NewTracker()
+	for path, m := range manifests {
+		tracker.add(path, m)
+	}
+	for i := 0; i < 2; i++ {
+		err, reloadDiscovery := create(context.TODO(), manifests, client, mapper, CreateOptions{Tracker: tracker})
+		if err == nil {
+			t.Fatalf("expected error")
+		}
+		if !reloadDiscovery {
+			t.Errorf("expected discovery reload for unknown kind")
+		}
+	}
+
+	expected := map[string]struct {
+		state    ManifestState
+		attempts int
+	}{
+		"0000_namespace.yaml":   {ManifestAlreadyExists, 1},
+		"0001_configmap.yaml":   {ManifestCreated, 1},
+		"0002_unknown.yaml":     {ManifestFailed, 2},
+		"0003_forbidden.yaml":   {ManifestFailed, 2},
+		"0004_with-status.yaml": {ManifestStatusUpdated, 1},
+	}
+	report := tracker.Report()
+	if report.Total != 5 || report.Done != 3 {
+		t.Errorf("expected 3/5 manifests done, got %d/%d", report.Done, report.Total)
+	}
+	for _, r := range report.Manifests {
+		want := expected[r.Path]
+		if r.State != want.state || r.Attempts != want.attempts {
+			t.Errorf("%s: expected %s after %d attempts, got %s after %d attempts", r.Path, want.state, want.attempts, r.State, r.Attempts)
+		}
+		if (r.State == ManifestFailed) != (len(r.LastError) > 0) {
+			t.Errorf("%s: unexpected last error %q in state %s", r.Path, r.LastError, r.State)
+		}
+	}
+	if _, ok := manifests["0001_configmap.yaml"]; ok {
+		t.Errorf("expected created manifest to be removed from the manifests")
+	}
+
+	summary := tracker.Summary()
+	for _, s := range []string{"v1/ConfigMap", "example.com/v1/Unknown", "3/5 manifests created, 0 skipped", `Failed "0003_forbidden.yaml" after 2 attempts: failed to create`} {
+		if !strings.Contains(summary, s) {
+			t.Errorf("expected summary to contain %q, got:\n%s", s, summary)
+		}
+	}
+}
+
+func TestTrackerKeepsDoneState(t *testing.T) {
+	tracker := NewTracker()
+	tracker.add("cm.yaml", testManifest("v1", "ConfigMap", "ns", "cm"))
+	tracker.attempt("cm.yaml")
+	tracker.succeeded("cm.yaml", ManifestCreated)
+
+	// a second creation run finds the object existing
+	tracker.add("cm.yaml", testManifest("v1", "ConfigMap", "ns", "cm"))
+	tracker.attempt("cm.yaml")
+	tracker.succeeded("cm.yaml", ManifestAlreadyExists)
+
+	report := tracker.Report()
+	if got := report.Manifests[0]; got.State != ManifestCreated || got.Attempts != 1 {
+		t.Errorf("expected Created after 1 attempt, got %s after %d attempts", got.State, got.Attempts)
+	}
+	if got := report.Manifests[0].GVK; got != gvkString(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}) {
+		t.Errorf("unexpected GVK %q", got)
+	}
+}
+
+func TestEnsureManifestsCreatedStrict(t *testing.T) {
+	tests := []struct {
+		name         string
+		manifest     string
+		strict       bool
+		wantAttempts func(int) bool
+	}{
+		{
+			name:         "strict stops after a failed attempt",
+			manifest:     "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: forbidden\n  namespace: ns\n",
+			strict:       true,
+			wantAttempts: func(n int) bool { return n == 1 },
+		},
+		{
+			name:         "strict retries unknown kinds",
+			manifest:     "apiVersion: example.com/v1\nkind: Unknown\nmetadata:\n  name: unknown\n",
+			strict:       true,
+			wantAttempts: func(n int) bool { return n > 1 },
+		},
+		{
+			name:         "non-strict retries until the context is done",
+			manifest:     "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: forbidden\n  namespace: ns\n",
+			wantAttempts: func(n int) bool { return n > 1 },
+		},
+	}
+
+	defer func(newClients func(*rest.Config) (dynamic.Interface, *discovery.DiscoveryClient, error), fetchDiscovery func(*discovery.DiscoveryClient) (meta.RESTMapper, error)) {
+		newClientsFn, fetchLatestDiscoveryInfoFn = newClients, fetchDiscovery
+	}(newClientsFn, fetchLatestDiscoveryInfoFn)
+	fetchLatestDiscoveryInfoFn = func(*discovery.DiscoveryClient) (meta.RESTMapper, error) {
+		return restmapper.NewDiscoveryRESTMapper(testResources), nil
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "manifest.yaml"), []byte(tt.manifest), 0644); err != nil {
+				t.Fatal(err)
+			}
+			client := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+			client.PrependReactor("create", "configmaps", func(action ktesting.Action) (bool, runtime.Object, error) {
+				return true, nil, errors.New("forbidden")
+			})
+			newClientsFn = func(*rest.Config) (dynamic.Interface, *discovery.DiscoveryClient, error) {
+				return client, nil, nil
+			}
+
+			ctx, cancel := context.WithTimeout(context.TODO(), time.Second)
+			defer cancel()
+			tracker := NewTracker()
+			err := EnsureManifestsCreated(ctx, dir, &rest.Config{}, CreateOptions{StdErr: io.Discard, Tracker: tracker, Strict: tt.strict})
+			var notCreated *NotCreatedError
+			if !errors.As(err, &notCreated) {
+				t.Fatalf("expected NotCreatedError, got %v", err)
+			}
+			if attempts := tracker.Report().Manifests[0].Attempts; !tt.wantAttempts(attempts) {
+				t.Errorf("unexpected number of attempts %d", attempts)
+			}
+		})
+	}
+}
+
+func TestLoadOverrides(t *testing.T) {
+	writeManifests := func(dir string, files map[string]string) {
+		for name, content := range files {
+			if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	base, platform, user := t.TempDir(), t.TempDir(), t.TempDir()
+	writeManifests(base, map[string]string{
+		"cm.yaml":     "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: cm\n  namespace: ns\ndata:\n  layer: base\n",
+		"cm-dup.yaml": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: cm\n  namespace: ns\ndata:\n  layer: base\n",
+		"other.yaml":  "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: cm\n  namespace: other\n",
+		// a duplicate within the same layer is created, but warned about
+		"other-dup.yaml": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: cm\n  namespace: other\n",
+		"ns.yaml":        "apiVersion: v1\nkind: Namespace\nmetadata:\n  name: ns\n",
+	})
+	writeManifests(platform, map[string]string{
+		"cm.yaml": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: cm\n  namespace: ns\ndata:\n  layer: platform\n",
+		"ns.yaml": "apiVersion: v1\nkind: Namespace\nmetadata:\n  name: ns\n  labels:\n    layer: platform\n",
+	})
+	writeManifests(user, map[string]string{
+		// a different file name and API version still names the same object
+		"my-cm.yaml": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: cm\n  namespace: ns\ndata:\n  layer: user\n",
+		// filtered out, so it must not override the platform layer
+		"ns.yaml": "apiVersion: v1\nkind: Namespace\nmetadata:\n  name: ns\n  annotations:\n    release.openshift.io/feature-set: TechPreviewNoUpgrade\n",
+	})
+
+	tracker := NewTracker()
+	var stdErr strings.Builder
+	manifests, err := load(base, CreateOptions{
+		StdErr:          &stdErr,
+		OverrideDirs:    []string{platform, user},
+		ManifestFilters: []ManifestPredicate{FeatureSet("")},
+		Tracker:         tracker,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{"other.yaml", "other-dup.yaml", filepath.Join(platform, "ns.yaml"), filepath.Join(user, "my-cm.yaml")}
+	sort.Strings(expected)
+	var got []string
+	for path := range manifests {
+		got = append(got, path)
+	}
+	sort.Strings(got)
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected manifests %v, got %v", expected, got)
+	}
+
+	report := tracker.Report()
+	if report.Total != 4 || report.Skipped != 5 {
+		t.Errorf("expected 4 manifests and 5 skipped, got %d and %d", report.Total, report.Skipped)
+	}
+	if !strings.Contains(stdErr.String(), "Warning: ConfigMap/other/cm is defined by several manifests of the same layer, all of them are created: other-dup.yaml, other.yaml\n") {
+		t.Errorf("expected a warning about the duplicates of the same layer, got:\n%s", stdErr.String())
+	}
+	if strings.Contains(stdErr.String(), "ConfigMap/ns/cm is defined") {
+		t.Errorf("expected no warning about overridden duplicates, got:\n%s", stdErr.String())
+	}
+	for _, r := range report.Manifests {
+		if r.Path == "cm.yaml" && r.Reason != fmt.Sprintf("it is overridden by %q", filepath.Join(user, "my-cm.yaml")) {
+			t.Errorf("unexpected skip reason for cm.yaml: %q", r.Reason)
+		}
+	}
+}
+
+func TestDecodeManifests(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		expected    []string
+		expectedErr string
+	}{
+		{
+			name:     "single object",
+			content:  "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: a\n",
+			expected: []string{"m.yaml=ConfigMap/a"},
+		},
+		{
+			name:     "single object with separators and comments",
+			content:  "---\n# a comment\n---\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: a\n---\n",
+			expected: []string{"m.yaml=ConfigMap/a"},
+		},
+		{
+			name:     "multiple documents",
+			content:  "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: a\n---\napiVersion: v1\nkind: Namespace\nmetadata:\n  name: b\n",
+			expected: []string{"m.yaml#0=ConfigMap/a", "m.yaml#1=Namespace/b"},
+		},
+		{
+			name:     "list",
+			content:  "apiVersion: v1\nkind: List\nitems:\n- apiVersion: v1\n  kind: ConfigMap\n  metadata:\n    name: a\n",
+			expected: []string{"m.yaml#0=ConfigMap/a"},
+		},
+		{
+			name:     "list and document",
+			content:  "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: a\n---\napiVersion: v1\nkind: List\nitems:\n- apiVersion: v1\n  kind: ConfigMap\n  metadata:\n    name: b\n- apiVersion: v1\n  kind: ConfigMap\n  metadata:\n    name: c\n",
+			expected: []string{"m.yaml#0=ConfigMap/a", "m.yaml#1=ConfigMap/b", "m.yaml#2=ConfigMap/c"},
+		},
+		{
+			name:     "empty list",
+			content:  "apiVersion: v1\nkind: List\nitems: []\n",
+			expected: []string{},
+		},
+		{
+			name:        "empty file",
+			content:     "# nothing\n",
+			expectedErr: "no objects found",
+		},
+		{
+			name:        "non-v1 list",
+			content:     "apiVersion: example.com/v1\nkind: List\nitems: []\n",
+			expectedErr: "only v1 lists are supported",
+		},
+		{
+			name:        "broken document",
+			content:     "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: a\n---\n{{ not a manifest\n",
+			expectedErr: "document 1",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			objects, err := decodeManifests("m.yaml", []byte(test.content))
+			if test.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.expectedErr) {
+					t.Fatalf("expected error containing %q, got %v", test.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got := []string{}
+			for path, obj := range objects {
+				got = append(got, fmt.Sprintf("%s=%s/%s", path, obj.GetKind(), obj.GetName()))
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestDecodeManifestsKeepsDocumentOrder(t *testing.T) {
+	var docs, expected []string
+	for i := 0; i < 11; i++ {
+		name := fmt.Sprintf("cm-%d", i)
+		docs = append(docs, "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: "+name+"\n")
+		expected = append(expected, name)
+	}
+	objects, err := decodeManifests("m.yaml", []byte(strings.Join(docs, "---\n")))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// manifests are created in the order of their sorted keys
+	var keys []string
+	for key := range objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	if keys[0] != "m.yaml#00" || keys[10] != "m.yaml#10" {
+		t.Errorf("expected keys m.yaml#00 to m.yaml#10, got %v", keys)
+	}
+	var got []string
+	for _, key := range keys {
+		got = append(got, objects[key].GetName())
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected objects in document order %v, got %v", expected, got)
+	}
+}
+
+func TestEnsureManifestsCreatedLoadError(t *testing.T) {
+	err := EnsureManifestsCreated(context.Background(), filepath.Join(t.TempDir(), "missing"), &rest.Config{}, CreateOptions{StdErr: io.Discard})
+	var notCreated *NotCreatedError
+	if err == nil || errors.As(err, &notCreated) {
+		t.Errorf("expected a load error other than NotCreatedError, got %v", err)
+	}
+}
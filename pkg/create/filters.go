@@ -0,0 +1,86 @@
+package create
+
+import (
+	"fmt"
+	"strings"
+
+	configv1 "github.com/openshift/api/config/v1"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+const (
+	featureSetAnnotation = "release.openshift.io/feature-set"
+	capabilityAnnotation = "capability.openshift.io/name"
+)
+
+// PathPredicate decides whether the manifest file at the given path, relative to the
+// manifests directory, is loaded. If it is not, the returned reason explains why it is skipped.
+type PathPredicate func(path string) (include bool, reason string)
+
+// ManifestPredicate decides whether a loaded manifest is created, given the path of its file
+// relative to the manifests directory. If it is not, the returned reason explains why it is skipped.
+type ManifestPredicate func(path string, manifest *unstructured.Unstructured) (include bool, reason string)
+
+// FeatureSet returns a predicate that skips manifests whose release.openshift.io/feature-set
+// annotation does not list the given feature set. Manifests without the annotation are included.
+// The empty feature set is the Default one.
+func FeatureSet(featureSet configv1.FeatureSet) ManifestPredicate {
+	target := "Default"
+	if len(featureSet) > 0 {
+		target = string(featureSet)
+	}
+	return func(_ string, manifest *unstructured.Unstructured) (bool, string) {
+		manifestFeatureSets, ok := manifest.GetAnnotations()[featureSetAnnotation]
+		if !ok {
+			return true, ""
+		}
+		for _, fs := range strings.Split(manifestFeatureSets, ",") {
+			if strings.TrimSpace(fs) == target {
+				return true, ""
+			}
+		}
+		return false, fmt.Sprintf("%s=%q does not include the cluster feature set %q", featureSetAnnotation, manifestFeatureSets, target)
+	}
+}
+
+// Capabilities returns a predicate that skips manifests whose capability.openshift.io/name
+// annotation names a disabled capability. The annotation may name several capabilities
+// joined by "+", all of which have to be enabled. Capabilities not in known are never
+// considered disabled, as they are newer than this binary.
+func Capabilities(enabled, known []configv1.ClusterVersionCapability) ManifestPredicate {
+	disabled := sets.NewString()
+	for _, c := range known {
+		disabled.Insert(string(c))
+	}
+	for _, c := range enabled {
+		disabled.Delete(string(c))
+	}
+	return func(_ string, manifest *unstructured.Unstructured) (bool, string) {
+		capabilities, ok := manifest.GetAnnotations()[capabilityAnnotation]
+		if !ok {
+			return true, ""
+		}
+		for _, c := range strings.Split(capabilities, "+") {
+			if disabled.Has(strings.TrimSpace(c)) {
+				return false, fmt.Sprintf("%s=%q requires the disabled capability %q", capabilityAnnotation, capabilities, c)
+			}
+		}
+		return true, ""
+	}
+}
+
+// EnabledCapabilities returns the capabilities enabled by the baseline capability set and the
+// additionally enabled capabilities. An empty baseline is the vCurrent set.
+func EnabledCapabilities(baseline configv1.ClusterVersionCapabilitySet, additional []configv1.ClusterVersionCapability) ([]configv1.ClusterVersionCapability, error) {
+	if len(baseline) == 0 {
+		baseline = configv1.ClusterVersionCapabilitySetCurrent
+	}
+	baselineCapabilities, ok := configv1.ClusterVersionCapabilitySets[baseline]
+	if !ok {
+		return nil, fmt.Errorf("unknown baseline capability set %q", baseline)
+	}
+	enabled := append([]configv1.ClusterVersionCapability{}, baselineCapabilities...)
+	return append(enabled, additional...), nil
+}
@@ -0,0 +1,79 @@
+package create
+
+import (
+	"testing"
+
+	configv1 "github.com/openshift/api/config/v1"
+)
+
+func TestFeatureSet(t *testing.T) {
+	tests := []struct {
+		name       string
+		featureSet configv1.FeatureSet
+		annotation *string
+		include    bool
+	}{
+		{"no annotation", "", nil, true},
+		{"default cluster, default manifest", "", strPtr("Default"), true},
+		{"default cluster, tech preview manifest", "", strPtr("TechPreviewNoUpgrade"), false},
+		{"tech preview cluster, tech preview manifest", configv1.TechPreviewNoUpgrade, strPtr("Default,TechPreviewNoUpgrade"), true},
+		{"tech preview cluster, default manifest", configv1.TechPreviewNoUpgrade, strPtr("Default"), false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m := testManifest("v1", "ConfigMap", "ns", "cm")
+			if test.annotation != nil {
+				m.SetAnnotations(map[string]string{featureSetAnnotation: *test.annotation})
+			}
+			include, reason := FeatureSet(test.featureSet)("cm.yaml", m)
+			if include != test.include {
+				t.Errorf("expected include=%v, got %v", test.include, include)
+			}
+			if !include && len(reason) == 0 {
+				t.Errorf("expected a reason for skipping")
+			}
+		})
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	enabled, err := EnabledCapabilities(configv1.ClusterVersionCapabilitySetNone, []configv1.ClusterVersionCapability{configv1.ClusterVersionCapabilityConsole})
+	if err != nil {
+		t.Fatal(err)
+	}
+	known := configv1.KnownClusterVersionCapabilities
+	tests := []struct {
+		name       string
+		annotation *string
+		include    bool
+	}{
+		{"no annotation", nil, true},
+		{"enabled", strPtr("Console"), true},
+		{"disabled", strPtr("Insights"), false},
+		{"enabled and disabled", strPtr("Console+Insights"), false},
+		{"unknown", strPtr("SomethingNew"), true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m := testManifest("v1", "ConfigMap", "ns", "cm")
+			if test.annotation != nil {
+				m.SetAnnotations(map[string]string{capabilityAnnotation: *test.annotation})
+			}
+			include, reason := Capabilities(enabled, known)("cm.yaml", m)
+			if include != test.include {
+				t.Errorf("expected include=%v, got %v", test.include, include)
+			}
+			if !include && len(reason) == 0 {
+				t.Errorf("expected a reason for skipping")
+			}
+		})
+	}
+
+	if _, err := EnabledCapabilities("v0.1", nil); err == nil {
+		t.Errorf("expected error for unknown baseline capability set")
+	}
+}
+
+func strPtr(s string) *string {
+	return &s
+}
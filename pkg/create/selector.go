@@ -0,0 +1,152 @@
+package create
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// SelectorField is the manifest property a selector matches on.
+type SelectorField string
+
+const (
+	// SelectorPath matches the manifest file path relative to the manifests directory.
+	SelectorPath SelectorField = "path"
+	// SelectorGVK matches <group>/<version>/<kind>, or <version>/<kind> for the core group,
+	// as printed in the asset creation summary.
+	SelectorGVK SelectorField = "gvk"
+	// SelectorNamespace matches the namespace of the manifest object.
+	SelectorNamespace SelectorField = "namespace"
+)
+
+// Selector matches manifests whose field matches a glob pattern, see path.Match.
+type Selector struct {
+	Field   SelectorField
+	Pattern string
+}
+
+// ParseSelector parses <field>=<glob> with field being one of path, gvk or namespace.
+func ParseSelector(s string) (Selector, error) {
+	ss := strings.SplitN(s, "=", 2)
+	if len(ss) != 2 || len(ss[1]) == 0 {
+		return Selector{}, fmt.Errorf("manifest selector must be of the form <field>=<glob>, got %q", s)
+	}
+	sel := Selector{Field: SelectorField(ss[0]), Pattern: ss[1]}
+	switch sel.Field {
+	case SelectorPath, SelectorGVK, SelectorNamespace:
+	default:
+		return Selector{}, fmt.Errorf("manifest selector field must be one of path, gvk or namespace, got %q", s)
+	}
+	if _, err := path.Match(sel.Pattern, ""); err != nil {
+		return Selector{}, fmt.Errorf("invalid manifest selector %q: %v", s, err)
+	}
+	return sel, nil
+}
+
+func (s Selector) String() string {
+	return fmt.Sprintf("%s=%s", s.Field, s.Pattern)
+}
+
+// matchesPath returns whether the selector matches the path. It is always false for non-path selectors.
+func (s Selector) matchesPath(p string) bool {
+	if s.Field != SelectorPath {
+		return false
+	}
+	matched, _ := path.Match(s.Pattern, filepath.ToSlash(p))
+	return matched
+}
+
+func (s Selector) matches(p string, manifest *unstructured.Unstructured) bool {
+	var value string
+	switch s.Field {
+	case SelectorPath:
+		return s.matchesPath(p)
+	case SelectorGVK:
+		value = gvkString(manifest.GroupVersionKind())
+	case SelectorNamespace:
+		value = manifest.GetNamespace()
+	}
+	matched, _ := path.Match(s.Pattern, value)
+	return matched
+}
+
+// Selection selects the manifests which match any of the include selectors, or all if
+// there are none, and which match none of the exclude selectors.
+type Selection struct {
+	Include []Selector
+	Exclude []Selector
+}
+
+// Empty returns true if the selection selects all manifests.
+func (s Selection) Empty() bool {
+	return len(s.Include) == 0 && len(s.Exclude) == 0
+}
+
+func (s Selection) String() string {
+	include := "all manifests"
+	if len(s.Include) > 0 {
+		include = "manifests matching any of " + joinSelectors(s.Include)
+	}
+	if len(s.Exclude) == 0 {
+		return include
+	}
+	return fmt.Sprintf("%s, except those matching any of %s", include, joinSelectors(s.Exclude))
+}
+
+// PathFilter returns a predicate which decides on the selection as far as possible by the
+// manifest path alone. This allows to exclude files which cannot even be decoded.
+func (s Selection) PathFilter() PathPredicate {
+	includeByPath := len(s.Include) > 0
+	for _, sel := range s.Include {
+		if sel.Field != SelectorPath {
+			includeByPath = false
+		}
+	}
+	return func(p string) (bool, string) {
+		for _, sel := range s.Exclude {
+			if sel.matchesPath(p) {
+				return false, fmt.Sprintf("it matches the exclude selector %s", sel)
+			}
+		}
+		if !includeByPath {
+			return true, ""
+		}
+		for _, sel := range s.Include {
+			if sel.matchesPath(p) {
+				return true, ""
+			}
+		}
+		return false, "it matches none of the include selectors"
+	}
+}
+
+// ManifestFilter returns a predicate implementing the selection.
+func (s Selection) ManifestFilter() ManifestPredicate {
+	return func(p string, manifest *unstructured.Unstructured) (bool, string) {
+		for _, sel := range s.Exclude {
+			if sel.matches(p, manifest) {
+				return false, fmt.Sprintf("it matches the exclude selector %s", sel)
+			}
+		}
+		if len(s.Include) == 0 {
+			return true, ""
+		}
+		for _, sel := range s.Include {
+			if sel.matches(p, manifest) {
+				return true, ""
+			}
+		}
+		return false, "it matches none of the include selectors"
+	}
+}
+
+func joinSelectors(selectors []Selector) string {
+	ss := make([]string, 0, len(selectors))
+	for _, s := range selectors {
+		ss = append(ss, s.String())
+	}
+	return "[" + strings.Join(ss, ", ") + "]"
+}
@@ -0,0 +1,119 @@
+package create
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseSelector(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Selector
+		wantErr bool
+	}{
+		{"path=0000_*.yaml", Selector{SelectorPath, "0000_*.yaml"}, false},
+		{"gvk=*/v1/Secret", Selector{SelectorGVK, "*/v1/Secret"}, false},
+		{"namespace=openshift-*", Selector{SelectorNamespace, "openshift-*"}, false},
+		{"namespace=", Selector{}, true},
+		{"kind=Secret", Selector{}, true},
+		{"path", Selector{}, true},
+		{"path=[", Selector{}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			got, err := ParseSelector(test.in)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("ParseSelector() error = %v, wantErr %v", err, test.wantErr)
+			}
+			if got != test.want {
+				t.Errorf("ParseSelector() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSelection(t *testing.T) {
+	secret := testManifest("v1", "Secret", "openshift-config", "pull-secret")
+	deployment := testManifest("apps/v1", "Deployment", "kube-system", "foo")
+	tests := []struct {
+		name      string
+		selection Selection
+		path      string
+		byPath    bool
+		include   bool
+	}{
+		{
+			name:    "empty",
+			path:    "secret.yaml",
+			byPath:  true,
+			include: true,
+		},
+		{
+			name:      "excluded path",
+			selection: Selection{Exclude: []Selector{{SelectorPath, "sec*.yaml"}}},
+			path:      "secret.yaml",
+			byPath:    false,
+			include:   false,
+		},
+		{
+			name:      "path not included",
+			selection: Selection{Include: []Selector{{SelectorPath, "0000_*"}}},
+			path:      "secret.yaml",
+			byPath:    false,
+			include:   false,
+		},
+		{
+			name:      "gvk included",
+			selection: Selection{Include: []Selector{{SelectorPath, "0000_*"}, {SelectorGVK, "v1/Secret"}}},
+			path:      "secret.yaml",
+			byPath:    true,
+			include:   true,
+		},
+		{
+			name:      "namespace excluded",
+			selection: Selection{Include: []Selector{{SelectorGVK, "v1/Secret"}}, Exclude: []Selector{{SelectorNamespace, "openshift-*"}}},
+			path:      "secret.yaml",
+			byPath:    true,
+			include:   false,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if byPath, _ := test.selection.PathFilter()(test.path); byPath != test.byPath {
+				t.Errorf("PathFilter() = %v, want %v", byPath, test.byPath)
+			}
+			if include, _ := test.selection.ManifestFilter()(test.path, secret); include != test.include {
+				t.Errorf("ManifestFilter() = %v, want %v", include, test.include)
+			}
+		})
+	}
+
+	selection := Selection{Include: []Selector{{SelectorGVK, "apps/*/Deployment"}}}
+	if include, _ := selection.ManifestFilter()("deployment.yaml", deployment); !include {
+		t.Errorf("expected deployment to be included by %s", selection)
+	}
+}
+
+func TestLoadSkipsExcludedPaths(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "cm.yaml"), []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: cm\n  namespace: ns\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "broken.yaml"), []byte("{{ not a manifest"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tracker := NewTracker()
+	selection := Selection{Exclude: []Selector{{SelectorPath, "broken.yaml"}}}
+	manifests, err := load(dir, CreateOptions{PathFilters: []PathPredicate{selection.PathFilter()}, Tracker: tracker})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(manifests) != 1 || manifests["cm.yaml"] == nil {
+		t.Errorf("expected only cm.yaml to be loaded, got %v", manifests)
+	}
+	if report := tracker.Report(); report.Total != 1 || report.Skipped != 1 {
+		t.Errorf("expected 1 manifest and 1 skipped, got %d and %d", report.Total, report.Skipped)
+	}
+}
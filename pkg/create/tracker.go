@@ -0,0 +1,248 @@
+package create
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"text/tabwriter"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ManifestState is the creation state of a single manifest.
+type ManifestState string
+
+const (
+	// ManifestPending means creation has not been attempted or has not finished yet.
+	ManifestPending ManifestState = "Pending"
+	// ManifestCreated means the object has been created.
+	ManifestCreated ManifestState = "Created"
+	// ManifestAlreadyExists means the object existed in the cluster already.
+	ManifestAlreadyExists ManifestState = "AlreadyExists"
+	// ManifestStatusUpdated means the object has been created or existed, and its status has been set from the manifest.
+	ManifestStatusUpdated ManifestState = "StatusUpdated"
+	// ManifestFailed means the last creation attempt has failed.
+	ManifestFailed ManifestState = "Failed"
+	// ManifestSkipped means the manifest has been excluded by a filter and is not created.
+	ManifestSkipped ManifestState = "Skipped"
+)
+
+// done returns true if the object of the manifest is known to exist in the cluster.
+func (s ManifestState) done() bool {
+	return s == ManifestCreated || s == ManifestAlreadyExists || s == ManifestStatusUpdated
+}
+
+// ManifestResult is the creation outcome of a single manifest.
+type ManifestResult struct {
+	Path      string        `json:"path"`
+	GVK       string        `json:"gvk,omitempty"`
+	Namespace string        `json:"namespace,omitempty"`
+	Name      string        `json:"name,omitempty"`
+	State     ManifestState `json:"state"`
+	Attempts  int           `json:"attempts"`
+	LastError string        `json:"lastError,omitempty"`
+	Reason    string        `json:"reason,omitempty"`
+}
+
+// Report is the creation outcome of all manifests. Skipped manifests are not part of the total.
+type Report struct {
+	Total     int              `json:"total"`
+	Done      int              `json:"done"`
+	Skipped   int              `json:"skipped"`
+	Manifests []ManifestResult `json:"manifests"`
+}
+
+// Tracker records the creation outcome of every manifest. It is safe for concurrent use,
+// and can be shared by multiple EnsureManifestsCreated calls for the same manifests.
+// Once a manifest is done, later results for it are ignored. A nil Tracker records nothing.
+type Tracker struct {
+	lock    sync.Mutex
+	results map[string]*ManifestResult
+}
+
+// NewTracker returns an empty tracker.
+func NewTracker() *Tracker {
+	return &Tracker{results: map[string]*ManifestResult{}}
+}
+
+func (t *Tracker) add(path string, manifest *unstructured.Unstructured) {
+	t.register(path, manifest, ManifestPending, "")
+}
+
+func (t *Tracker) skip(path string, manifest *unstructured.Unstructured, reason string) {
+	t.register(path, manifest, ManifestSkipped, reason)
+}
+
+func (t *Tracker) register(path string, manifest *unstructured.Unstructured, state ManifestState, reason string) {
+	if t == nil {
+		return
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if _, ok := t.results[path]; ok {
+		return
+	}
+	r := &ManifestResult{
+		Path:   path,
+		State:  state,
+		Reason: reason,
+	}
+	// manifests skipped before being decoded are unknown
+	if manifest != nil {
+		r.GVK = gvkString(manifest.GroupVersionKind())
+		r.Namespace = manifest.GetNamespace()
+		r.Name = manifest.GetName()
+	}
+	t.results[path] = r
+}
+
+func (t *Tracker) attempt(path string) {
+	t.update(path, func(r *ManifestResult) {
+		r.Attempts++
+	})
+}
+
+func (t *Tracker) succeeded(path string, state ManifestState) {
+	t.update(path, func(r *ManifestResult) {
+		r.State = state
+		r.LastError = ""
+	})
+}
+
+func (t *Tracker) failed(path string, err error) {
+	t.update(path, func(r *ManifestResult) {
+		r.State = ManifestFailed
+		r.LastError = err.Error()
+	})
+}
+
+func (t *Tracker) update(path string, fn func(r *ManifestResult)) {
+	if t == nil {
+		return
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	r, ok := t.results[path]
+	if !ok || r.State.done() || r.State == ManifestSkipped {
+		return
+	}
+	fn(r)
+}
+
+// Progress returns the number of manifests whose objects exist in the cluster, and the
+// total number of manifests.
+func (t *Tracker) Progress() (done, total int) {
+	if t == nil {
+		return 0, 0
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	for _, r := range t.results {
+		switch {
+		case r.State.done():
+			done++
+		case r.State == ManifestSkipped:
+			continue
+		}
+		total++
+	}
+	return done, total
+}
+
+// Report returns a snapshot of the results, sorted by manifest path.
+func (t *Tracker) Report() Report {
+	report := Report{Manifests: []ManifestResult{}}
+	if t == nil {
+		return report
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	for _, r := range t.results {
+		report.Manifests = append(report.Manifests, *r)
+		switch {
+		case r.State.done():
+			report.Done++
+		case r.State == ManifestSkipped:
+			report.Skipped++
+			continue
+		}
+		report.Total++
+	}
+	sort.Slice(report.Manifests, func(i, j int) bool { return report.Manifests[i].Path < report.Manifests[j].Path })
+	return report
+}
+
+// WriteReport writes the results as JSON to the given file.
+func (t *Tracker) WriteReport(file string) error {
+	data, err := json.MarshalIndent(t.Report(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, append(data, '\n'), 0644)
+}
+
+// Summary returns a table with the number of manifests per GVK and state, followed by
+// the last error of every manifest that has not been created.
+func (t *Tracker) Summary() string {
+	report := t.Report()
+
+	states := []ManifestState{ManifestCreated, ManifestAlreadyExists, ManifestStatusUpdated, ManifestFailed, ManifestPending, ManifestSkipped}
+	counts := map[string]map[ManifestState]int{}
+	for _, r := range report.Manifests {
+		if counts[r.GVK] == nil {
+			counts[r.GVK] = map[ManifestState]int{}
+		}
+		counts[r.GVK][r.State]++
+	}
+	gvks := make([]string, 0, len(counts))
+	for gvk := range counts {
+		gvks = append(gvks, gvk)
+	}
+	sort.Strings(gvks)
+
+	buf := &bytes.Buffer{}
+	tw := tabwriter.NewWriter(buf, 0, 4, 2, ' ', 0)
+	fmt.Fprint(tw, "GVK")
+	for _, s := range states {
+		fmt.Fprintf(tw, "\t%s", s)
+	}
+	fmt.Fprintln(tw)
+	for _, gvk := range gvks {
+		fmt.Fprint(tw, gvk)
+		for _, s := range states {
+			fmt.Fprintf(tw, "\t%d", counts[gvk][s])
+		}
+		fmt.Fprintln(tw)
+	}
+	tw.Flush()
+
+	fmt.Fprintf(buf, "%d/%d manifests created, %d skipped\n", report.Done, report.Total, report.Skipped)
+	for _, r := range report.Manifests {
+		switch {
+		case r.State.done():
+			continue
+		case r.State == ManifestSkipped:
+			fmt.Fprintf(buf, "\t%s %q as %s", r.State, r.Path, r.Reason)
+		default:
+			fmt.Fprintf(buf, "\t%s %q after %d attempts", r.State, r.Path, r.Attempts)
+			if len(r.LastError) > 0 {
+				fmt.Fprintf(buf, ": %s", r.LastError)
+			}
+		}
+		fmt.Fprintln(buf)
+	}
+	return buf.String()
+}
+
+// gvkString formats a GVK as <group>/<version>/<kind>, or <version>/<kind> for the core group.
+func gvkString(gvk schema.GroupVersionKind) string {
+	return gvk.GroupVersion().String() + "/" + gvk.Kind
+}
@@ -0,0 +1,78 @@
+// Package installconfig reads the install config from the cluster-config ConfigMap manifest
+// written by the installer.
+package installconfig
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	configv1 "github.com/openshift/api/config/v1"
+	"github.com/openshift/installer/pkg/types"
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// ClusterConfigPath is the path of the cluster-config ConfigMap relative to the asset directory.
+const ClusterConfigPath = "manifests/cluster-config.yaml"
+
+// Features holds the install config fields that the vendored installer types do not know.
+type Features struct {
+	FeatureSet   configv1.FeatureSet `json:"featureSet,omitempty"`
+	Capabilities *struct {
+		BaselineCapabilitySet         configv1.ClusterVersionCapabilitySet `json:"baselineCapabilitySet,omitempty"`
+		AdditionalEnabledCapabilities []configv1.ClusterVersionCapability  `json:"additionalEnabledCapabilities,omitempty"`
+	} `json:"capabilities,omitempty"`
+}
+
+// Load returns the install config of the cluster-config ConfigMap manifest file.
+func Load(file string) (*types.InstallConfig, error) {
+	installConfigData, err := Data(file)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(installConfigData)
+}
+
+// Parse returns the install config of the raw install config YAML, e.g. of the install-config
+// key of the cluster-config-v1 ConfigMap in a running cluster.
+func Parse(installConfigData []byte) (*types.InstallConfig, error) {
+	installConfig := types.InstallConfig{}
+	if err := yaml.Unmarshal(installConfigData, &installConfig); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal install config %w", err)
+	}
+
+	return &installConfig, nil
+}
+
+// LoadFeatures returns the install config fields of Features of the cluster-config ConfigMap
+// manifest file.
+func LoadFeatures(file string) (*Features, error) {
+	installConfigData, err := Data(file)
+	if err != nil {
+		return nil, err
+	}
+
+	features := Features{}
+	if err := yaml.Unmarshal(installConfigData, &features); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal install config %w", err)
+	}
+
+	return &features, nil
+}
+
+// Data returns the raw install config YAML of the cluster-config ConfigMap manifest file.
+func Data(file string) ([]byte, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	cm := v1.ConfigMap{}
+	if err := yaml.Unmarshal(data, &cm); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal cluster config cm %w", err)
+	}
+	installConfigData, ok := cm.Data["install-config"]
+	if !ok {
+		return nil, fmt.Errorf("install-config doesn't exist in cluster config cm %w", err)
+	}
+	return []byte(installConfigData), nil
+}
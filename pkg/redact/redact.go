@@ -0,0 +1,219 @@
+// Package redact masks secrets, i.e. private keys, kubeconfig credentials and the contents of
+// the TLS and auth assets, in text which is printed or collected for support.
+package redact
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/vincent-petithory/dataurl"
+)
+
+// Mask replaces redacted secrets.
+const Mask = "<redacted>"
+
+// TLSDir is the directory of the asset dir holding the TLS assets, whose contents are secret.
+const TLSDir = "tls"
+
+// AuthDir is the directory of the asset dir holding the kubeconfigs, whose contents are secret.
+const AuthDir = "auth"
+
+// minSecretLineLength is the minimum length of a line of a secret file to be redacted on its
+// own, so that short lines like "}" are not masked everywhere.
+const minSecretLineLength = 16
+
+var (
+	// pemPrivateKey matches PEM private key blocks, with literal or escaped spaces and line
+	// breaks, as in data URLs.
+	pemPrivateKey = regexp.MustCompile(`(?s)-----BEGIN((?: |%20)(?:[A-Z0-9]+(?: |%20))*)PRIVATE(?: |%20)KEY-----.*?-----END(?: |%20)(?:[A-Z0-9]+(?: |%20))*PRIVATE(?: |%20)KEY-----`)
+	// credential matches the credentials of kubeconfig users and of pull secrets in YAML or JSON.
+	// The key must start at a boundary, so that e.g. oauth is not matched, and only a scalar on
+	// the same line is masked, so that the first key of a nested YAML mapping is not.
+	credential = regexp.MustCompile(`(?m)((?:^|[\s{,"])"?(?:token|client-key-data|password|auth)"?[ \t]*:[ \t]*"?)[^\s",}]+`)
+)
+
+// Redactor masks private keys, kubeconfig and pull secret credentials and a set of known
+// secrets.
+type Redactor struct {
+	secrets []string
+}
+
+// New returns a Redactor which also masks the given secrets.
+func New(secrets ...string) *Redactor {
+	r := &Redactor{}
+	for _, s := range secrets {
+		r.AddSecret(s)
+	}
+	return r
+}
+
+// ForAssetDir returns a Redactor which also masks the contents of the files in the TLS and
+// auth directories of the asset dir, if any.
+func ForAssetDir(assetDir string) (*Redactor, error) {
+	r := New()
+	for _, dir := range []string{TLSDir, AuthDir} {
+		err := filepath.Walk(filepath.Join(assetDir, dir), func(path string, info os.FileInfo, err error) error {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if !info.Mode().IsRegular() {
+				return nil
+			}
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			r.AddSecret(string(data))
+			return nil
+		})
+		if err != nil {
+			return r, err
+		}
+	}
+	return r, nil
+}
+
+// AddSecret masks the secret, as well as its base64 and data URL encodings, and each of its
+// lines which is long enough to be distinctive.
+func (r *Redactor) AddSecret(secret string) {
+	if len(strings.TrimSpace(secret)) == 0 {
+		return
+	}
+	r.secrets = append(r.secrets,
+		secret,
+		base64.StdEncoding.EncodeToString([]byte(secret)),
+		dataurl.EscapeString(secret),
+	)
+	for _, line := range strings.Split(secret, "\n") {
+		if line = strings.TrimSpace(line); len(line) >= minSecretLineLength {
+			r.secrets = append(r.secrets, line)
+		}
+	}
+	// replace longer secrets first, as they may contain shorter ones
+	sort.SliceStable(r.secrets, func(i, j int) bool { return len(r.secrets[i]) > len(r.secrets[j]) })
+}
+
+// String returns s with all secrets masked.
+func (r *Redactor) String(s string) string {
+	if r != nil {
+		for _, secret := range r.secrets {
+			s = strings.ReplaceAll(s, secret, Mask)
+		}
+	}
+	s = pemPrivateKey.ReplaceAllString(s, "-----BEGIN${1}PRIVATE KEY-----"+Mask+"-----END${1}PRIVATE KEY-----")
+	return credential.ReplaceAllString(s, "${1}"+Mask)
+}
+
+// Bytes returns data with all secrets masked.
+func (r *Redactor) Bytes(data []byte) []byte {
+	return []byte(r.String(string(data)))
+}
+
+// Ignition returns data with all secrets masked. If data is an Ignition config, the data URL
+// contents of its files are decoded and decompressed first, as secrets cannot be found in their
+// base64 or gzip encodings, and are written back as plain data URLs. Contents which cannot be
+// decoded are masked completely.
+func (r *Redactor) Ignition(data []byte) []byte {
+	var config map[string]interface{}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return r.Bytes(data)
+	}
+	storage, _ := config["storage"].(map[string]interface{})
+	files, _ := storage["files"].([]interface{})
+	if len(files) == 0 {
+		return r.Bytes(data)
+	}
+	for _, f := range files {
+		file, ok := f.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if contents, ok := file["contents"].(map[string]interface{}); ok {
+			r.ignitionResource(contents)
+		}
+		appends, _ := file["append"].([]interface{})
+		for _, a := range appends {
+			if resource, ok := a.(map[string]interface{}); ok {
+				r.ignitionResource(resource)
+			}
+		}
+	}
+	out, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return r.Bytes(data)
+	}
+	return r.Bytes(append(out, '\n'))
+}
+
+// ignitionResource replaces the data URL source of the Ignition resource by a plain one with
+// the redacted contents. Its compression and verification no longer apply and are removed.
+func (r *Redactor) ignitionResource(resource map[string]interface{}) {
+	source, _ := resource["source"].(string)
+	if !strings.HasPrefix(source, "data:") {
+		return
+	}
+	contents, err := decodeDataURL(source, resource["compression"])
+	if err != nil {
+		contents = Mask
+	} else {
+		contents = r.String(contents)
+	}
+	resource["source"] = "data:," + dataurl.EscapeString(contents)
+	delete(resource, "compression")
+	delete(resource, "verification")
+}
+
+// decodeDataURL returns the decoded and decompressed contents of the data URL.
+func decodeDataURL(source string, compression interface{}) (string, error) {
+	du, err := dataurl.DecodeString(source)
+	if err != nil {
+		return "", err
+	}
+	switch compression {
+	case nil, "":
+		return string(du.Data), nil
+	case "gzip":
+		zr, err := gzip.NewReader(bytes.NewReader(du.Data))
+		if err != nil {
+			return "", err
+		}
+		data, err := ioutil.ReadAll(zr)
+		return string(data), err
+	default:
+		return "", fmt.Errorf("unsupported compression %v", compression)
+	}
+}
+
+// Error returns err with its message redacted. It unwraps to err, so that errors.Is and
+// errors.As still work.
+func (r *Redactor) Error(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &redactedError{err: err, msg: r.String(err.Error())}
+}
+
+type redactedError struct {
+	err error
+	msg string
+}
+
+func (e *redactedError) Error() string {
+	return e.msg
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,141 @@
+package start
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// unpackAssetArchive unpacks the tar archive, optionally gzip compressed, into a new staging
+// directory only accessible by the current user, and returns its path. Only directories and
+// regular files are allowed, and they must not leave the staging directory. Their permission
+// bits are taken from the archive. The caller must remove the staging directory.
+func unpackAssetArchive(archive string) (string, error) {
+	f, err := os.Open(archive)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	var in io.Reader = r
+	if magic, err := r.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return "", fmt.Errorf("failed to decompress asset archive %s: %w", archive, err)
+		}
+		defer gz.Close()
+		in = gz
+	}
+
+	stagingDir, err := ioutil.TempDir("", "cluster-bootstrap-assets-")
+	if err != nil {
+		return "", err
+	}
+	if err := os.Chmod(stagingDir, 0700); err != nil {
+		os.RemoveAll(stagingDir)
+		return "", err
+	}
+	if err := untar(tar.NewReader(in), stagingDir); err != nil {
+		removeStagingDir(stagingDir)
+		return "", fmt.Errorf("failed to unpack asset archive %s: %w", archive, err)
+	}
+
+	UserOutput("Unpacked asset archive %s to %s\n", archive, stagingDir)
+	return stagingDir, nil
+}
+
+func untar(tr *tar.Reader, dstDir string) error {
+	// directory modes are applied last, so that read-only directories can be filled
+	dirModes := map[string]os.FileMode{}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		dst, err := archiveEntryPath(dstDir, hdr.Name)
+		if err != nil {
+			return err
+		}
+		mode := os.FileMode(hdr.Mode).Perm()
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(dst, 0700); err != nil {
+				return err
+			}
+			// the staging directory itself stays private
+			if dst != dstDir {
+				dirModes[dst] = mode
+			}
+		case tar.TypeReg, tar.TypeRegA:
+			if err := os.MkdirAll(filepath.Dir(dst), 0700); err != nil {
+				return err
+			}
+			if err := writeArchiveFile(tr, dst, mode); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("%s: unsupported entry type %q, only directories and regular files are allowed", hdr.Name, hdr.Typeflag)
+		}
+	}
+
+	for dir, mode := range dirModes {
+		if err := os.Chmod(dir, mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// archiveEntryPath returns the destination of the archive entry name within dstDir, or an
+// error if it would end up outside of dstDir.
+func archiveEntryPath(dstDir, name string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(name))
+	if filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s: path is outside of the asset directory", name)
+	}
+	return filepath.Join(dstDir, clean), nil
+}
+
+func writeArchiveFile(r io.Reader, dst string, mode os.FileMode) error {
+	f, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Chmod(dst, mode)
+}
+
+// removeStagingDir removes the staging directory, including read-only directories unpacked into it.
+func removeStagingDir(dir string) error {
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return os.Chmod(path, 0700)
+		}
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return os.RemoveAll(dir)
+}
@@ -0,0 +1,169 @@
+package start
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type archiveEntry struct {
+	name     string
+	typeflag byte
+	mode     int64
+	content  string
+	linkname string
+}
+
+func writeTestArchive(t *testing.T, dir string, compress bool, entries []archiveEntry) string {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Typeflag: e.typeflag, Mode: e.mode, Linkname: e.linkname}
+		if e.typeflag == tar.TypeReg {
+			hdr.Size = int64(len(e.content))
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if e.typeflag == tar.TypeReg {
+			if _, err := tw.Write([]byte(e.content)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data := buf.Bytes()
+	name := "assets.tar"
+	if compress {
+		var gzBuf bytes.Buffer
+		gz := gzip.NewWriter(&gzBuf)
+		if _, err := gz.Write(data); err != nil {
+			t.Fatal(err)
+		}
+		if err := gz.Close(); err != nil {
+			t.Fatal(err)
+		}
+		data = gzBuf.Bytes()
+		name = "assets.tar.gz"
+	}
+
+	archive := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(archive, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return archive
+}
+
+func TestUnpackAssetArchive(t *testing.T) {
+	validEntries := []archiveEntry{
+		{name: "./", typeflag: tar.TypeDir, mode: 0755},
+		{name: "tls/", typeflag: tar.TypeDir, mode: 0500},
+		{name: "tls/ca.key", typeflag: tar.TypeReg, mode: 0600, content: "key"},
+		{name: "manifests/cm.yaml", typeflag: tar.TypeReg, mode: 0644, content: "kind: ConfigMap"},
+	}
+
+	tests := []struct {
+		name        string
+		compress    bool
+		entries     []archiveEntry
+		expectedErr string
+	}{
+		{
+			name:    "tar",
+			entries: validEntries,
+		},
+		{
+			name:     "tar.gz",
+			compress: true,
+			entries:  validEntries,
+		},
+		{
+			name: "path traversal",
+			entries: []archiveEntry{
+				{name: "manifests/../../evil.yaml", typeflag: tar.TypeReg, mode: 0644, content: "evil"},
+			},
+			expectedErr: "path is outside of the asset directory",
+		},
+		{
+			name: "absolute path",
+			entries: []archiveEntry{
+				{name: "/etc/evil.yaml", typeflag: tar.TypeReg, mode: 0644, content: "evil"},
+			},
+			expectedErr: "path is outside of the asset directory",
+		},
+		{
+			name: "symlink",
+			entries: []archiveEntry{
+				{name: "manifests/link.yaml", typeflag: tar.TypeSymlink, mode: 0777, linkname: "/etc/passwd"},
+			},
+			expectedErr: "unsupported entry type",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			archive := writeTestArchive(t, t.TempDir(), test.compress, test.entries)
+
+			stagingDir, err := unpackAssetArchive(archive)
+			if test.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.expectedErr) {
+					t.Fatalf("expected error containing %q, got %v", test.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer removeStagingDir(stagingDir)
+
+			info, err := os.Stat(stagingDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Mode().Perm() != 0700 {
+				t.Errorf("expected staging directory mode 0700, got %v", info.Mode().Perm())
+			}
+			for _, e := range test.entries {
+				if e.typeflag != tar.TypeReg {
+					continue
+				}
+				path := filepath.Join(stagingDir, e.name)
+				data, err := ioutil.ReadFile(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(data) != e.content {
+					t.Errorf("%s: expected content %q, got %q", e.name, e.content, data)
+				}
+				info, err := os.Stat(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if info.Mode().Perm() != os.FileMode(e.mode) {
+					t.Errorf("%s: expected mode %v, got %v", e.name, os.FileMode(e.mode), info.Mode().Perm())
+				}
+			}
+			info, err = os.Stat(filepath.Join(stagingDir, "tls"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Mode().Perm() != 0500 {
+				t.Errorf("expected tls directory mode 0500, got %v", info.Mode().Perm())
+			}
+
+			if err := removeStagingDir(stagingDir); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := os.Stat(stagingDir); !os.IsNotExist(err) {
+				t.Errorf("expected staging directory to be removed, got %v", err)
+			}
+		})
+	}
+}
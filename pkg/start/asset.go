@@ -2,42 +2,54 @@ package start
 
 import (
 	"fmt"
-	"github.com/openshift/installer/pkg/types"
-	"io/ioutil"
-	v1 "k8s.io/api/core/v1"
-	"sigs.k8s.io/yaml"
+	"strings"
+
+	configv1 "github.com/openshift/api/config/v1"
+
+	"github.com/openshift/cluster-bootstrap/pkg/create"
+	"github.com/openshift/cluster-bootstrap/pkg/installconfig"
 )
 
 const (
 	assetPathSecrets            = "tls"
 	assetPathAdminKubeConfig    = "auth/kubeconfig-loopback"
-	assetPathClusterConfig      = "manifests/cluster-config.yaml"
+	assetPathClusterConfig      = installconfig.ClusterConfigPath
 	assetPathManifests          = "manifests"
 	assetPathBootstrapManifests = "bootstrap-manifests"
+	assetPathCreationReport     = "asset-creation-report.json"
 )
 
 var (
 	bootstrapSecretsDir = "/etc/kubernetes/bootstrap-secrets" // Overridden for testing.
 )
 
-func getInstallConfig(file string) (*types.InstallConfig, error) {
-	data, err := ioutil.ReadFile(file)
+// installConfigManifestFilters returns the predicates skipping the manifests of other
+// feature sets and of disabled capabilities, according to the install config.
+func installConfigManifestFilters(clusterConfigPath string) ([]create.ManifestPredicate, error) {
+	features, err := installconfig.LoadFeatures(clusterConfigPath)
 	if err != nil {
-		return nil, err
-	}
-	cm := v1.ConfigMap{}
-	if err := yaml.Unmarshal(data, &cm); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal cluster config cm %w", err)
-	}
-	installConfigData, ok := cm.Data["install-config"]
-	if !ok {
-		return nil, fmt.Errorf("install-config doesn't exist in cluster config cm %w", err)
+		return nil, fmt.Errorf("failed to get install config from cluster configmap: %w", err)
 	}
 
-	installConfig := types.InstallConfig{}
-	if err := yaml.Unmarshal([]byte(installConfigData), &installConfig); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal install config %w", err)
+	featureSet := features.FeatureSet
+	if len(featureSet) == 0 {
+		featureSet = "Default"
 	}
+	filters := []create.ManifestPredicate{create.FeatureSet(featureSet)}
+	UserOutput("Skipping manifests annotated for feature sets other than %q\n", featureSet)
 
-	return &installConfig, nil
+	if features.Capabilities == nil {
+		return filters, nil
+	}
+	enabled, err := create.EnabledCapabilities(features.Capabilities.BaselineCapabilitySet, features.Capabilities.AdditionalEnabledCapabilities)
+	if err != nil {
+		// creating the manifests of all capabilities would enable those disabled by the user
+		return nil, fmt.Errorf("failed to determine the enabled capabilities: %w", err)
+	}
+	names := make([]string, 0, len(enabled))
+	for _, c := range enabled {
+		names = append(names, string(c))
+	}
+	UserOutput("Skipping manifests of capabilities other than: %s\n", strings.Join(names, ", "))
+	return append(filters, create.Capabilities(enabled, configv1.KnownClusterVersionCapabilities)), nil
 }
@@ -0,0 +1,66 @@
+package start
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestInstallConfigManifestFilters(t *testing.T) {
+	tests := []struct {
+		name          string
+		installConfig string
+		expectedSkip  bool
+		expectErr     bool
+	}{
+		{
+			name:          "no capabilities",
+			installConfig: "apiVersion: v1\n",
+		},
+		{
+			name:          "disabled capability",
+			installConfig: "capabilities:\n  baselineCapabilitySet: None\n",
+			expectedSkip:  true,
+		},
+		{
+			name:          "additionally enabled capability",
+			installConfig: "capabilities:\n  baselineCapabilitySet: None\n  additionalEnabledCapabilities:\n  - Console\n",
+		},
+		{
+			name:          "unknown baseline capability set",
+			installConfig: "capabilities:\n  baselineCapabilitySet: v0.0\n  additionalEnabledCapabilities:\n  - Console\n",
+			expectErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clusterConfig := filepath.Join(t.TempDir(), "cluster-config.yaml")
+			writeTestFile(t, clusterConfig, "data:\n  install-config: |\n    "+strings.ReplaceAll(tt.installConfig, "\n", "\n    ")+"\n")
+
+			filters, err := installConfigManifestFilters(clusterConfig)
+			if tt.expectErr {
+				if err == nil {
+					t.Fatalf("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			console := &unstructured.Unstructured{}
+			console.SetAnnotations(map[string]string{"capability.openshift.io/name": "Console"})
+			skipped := false
+			for _, filter := range filters {
+				if include, _ := filter("console.yaml", console); !include {
+					skipped = true
+				}
+			}
+			if skipped != tt.expectedSkip {
+				t.Errorf("expected the Console manifest to be skipped %v, got %v", tt.expectedSkip, skipped)
+			}
+		})
+	}
+}
@@ -31,6 +31,13 @@ func newBootstrapControlPlane(assetDir, podManifestPath string, kubeApiHost stri
 	}
 }
 
+// NewStaticPodControlPlane returns the ControlPlaneLauncher of the start command, which copies
+// the bootstrap manifests of the asset dir to the pod manifest path of the kubelet and waits
+// for the API on kubeApiHost.
+func NewStaticPodControlPlane(assetDir, podManifestPath, kubeApiHost string) ControlPlaneLauncher {
+	return newBootstrapControlPlane(assetDir, podManifestPath, kubeApiHost)
+}
+
 // Start seeds static manifests to the kubelet to launch the bootstrap control plane.
 // Users should always ensure that Cleanup() is called even in the case of errors.
 func (b *bootstrapControlPlane) Start() error {
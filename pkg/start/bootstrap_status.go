@@ -0,0 +1,241 @@
+package start
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	configversionedclient "github.com/openshift/client-go/config/clientset/versioned"
+	operatorversionedclient "github.com/openshift/client-go/operator/clientset/versioned"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"github.com/openshift/cluster-bootstrap/pkg/installconfig"
+)
+
+// BootstrapPhase is how far the bootstrap of a cluster has progressed.
+type BootstrapPhase string
+
+const (
+	// PhasePodsPending means that the required pods are not all running and ready.
+	PhasePodsPending BootstrapPhase = "PodsPending"
+	// PhaseControlPlanePending means that the self-hosted control plane or the required cluster
+	// operators are not available yet.
+	PhaseControlPlanePending BootstrapPhase = "ControlPlanePending"
+	// PhaseControlPlaneAvailable means that all conditions for the bootstrap-success event are
+	// satisfied, but it has not been sent yet.
+	PhaseControlPlaneAvailable BootstrapPhase = "ControlPlaneAvailable"
+	// PhaseSucceeded means that the bootstrap-success event has been sent and the remaining
+	// assets are being created.
+	PhaseSucceeded BootstrapPhase = "Succeeded"
+	// PhaseFinished means that the bootstrap-finished event has been sent.
+	PhaseFinished BootstrapPhase = "Finished"
+)
+
+// StatusConfig configures QueryBootstrapStatus.
+type StatusConfig struct {
+	// RequiredPodPrefixes are the pods which must be running and ready, as for Config.
+	RequiredPodPrefixes map[string][]string
+	// ControlPlaneReplicas is the number of control plane replicas. If zero, it is read from
+	// the install config of the cluster.
+	ControlPlaneReplicas     int
+	RequiredClusterOperators []ClusterOperatorRequirement
+}
+
+// BootstrapStatus is the bootstrap progress of a cluster, as observed by the start command.
+type BootstrapStatus struct {
+	Phase      BootstrapPhase    `json:"phase"`
+	Events     []EventStatus     `json:"events"`
+	Pods       []PodStatus       `json:"pods"`
+	Conditions []ConditionStatus `json:"conditions"`
+}
+
+// EventStatus tells whether one of the bootstrap events has been sent.
+type EventStatus struct {
+	Name string       `json:"name"`
+	Sent bool         `json:"sent"`
+	Time *metav1.Time `json:"time,omitempty"`
+}
+
+// PodStatus is the status of a required pod, one of DoesNotExist, RunningNotReady, Ready or
+// the pod phase.
+type PodStatus struct {
+	Description string `json:"description"`
+	Status      string `json:"status"`
+	Ready       bool   `json:"ready"`
+}
+
+// ConditionStatus is the state of one of the conditions waited for before the
+// bootstrap-success event is sent.
+type ConditionStatus struct {
+	Condition string `json:"condition"`
+	Satisfied bool   `json:"satisfied"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// QueryBootstrapStatus checks once, without waiting, what the start command waits for: the
+// required pods, the availability of the self-hosted control plane, the required cluster
+// operators and the bootstrap events. Conditions which cannot be checked are reported as not
+// satisfied with the error as reason, while an error is returned if the pods or events cannot
+// be read.
+func QueryBootstrapStatus(ctx context.Context, restConfig *rest.Config, config StatusConfig) (*BootstrapStatus, error) {
+	client, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, err
+	}
+	operatorClient, err := operatorversionedclient.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("error creating operator client config: %w", err)
+	}
+	configClient, err := configversionedclient.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("error creating config client config: %w", err)
+	}
+
+	status := &BootstrapStatus{}
+	for _, name := range []string{bootstrapSuccessEvent, bootstrapFinishedEvent} {
+		event, err := client.CoreV1().Events(bootstrapEventNamespace).Get(ctx, name, metav1.GetOptions{})
+		switch {
+		case apierrors.IsNotFound(err):
+			status.Events = append(status.Events, EventStatus{Name: name})
+		case err != nil:
+			return nil, fmt.Errorf("error getting %s/%s event: %w", bootstrapEventNamespace, name, err)
+		default:
+			status.Events = append(status.Events, EventStatus{Name: name, Sent: true, Time: &event.LastTimestamp})
+		}
+	}
+
+	sc, err := newStatusController(client, config.RequiredPodPrefixes)
+	if err != nil {
+		return nil, err
+	}
+	if err := sc.List(ctx); err != nil {
+		return nil, fmt.Errorf("error listing pods: %w", err)
+	}
+	ps, err := sc.podStatus()
+	if err != nil {
+		return nil, err
+	}
+	status.Pods = podStatuses(ps)
+
+	replicas := config.ControlPlaneReplicas
+	var replicasErr error
+	if replicas == 0 {
+		replicas, replicasErr = clusterControlPlaneReplicas(ctx, client)
+	}
+	pollers := controlPlaneStatusPollers(operatorClient, replicas, replicasErr)
+	pollers = append(pollers, clusterOperatorPollers(configClient, config.RequiredClusterOperators, 0)...)
+	for _, p := range pollers {
+		reason, satisfied := p.condition(ctx)
+		status.Conditions = append(status.Conditions, ConditionStatus{Condition: p.what, Satisfied: satisfied, Reason: reason})
+	}
+
+	status.Phase = status.phase()
+	return status, nil
+}
+
+// controlPlaneStatusPollers returns the pollers of the availability of an HA control plane. If
+// the number of control plane replicas is unknown, its availability cannot be checked, which is
+// reported by a poller never satisfied with replicasErr as reason.
+func controlPlaneStatusPollers(operatorClient operatorversionedclient.Interface, replicas int, replicasErr error) []*poller {
+	if replicasErr != nil {
+		return []*poller{{
+			what: "control plane replicas should be known to check the availability of the control plane",
+			condition: func(context.Context) (string, bool) {
+				return replicasErr.Error(), false
+			},
+		}}
+	}
+	if !isHAControlPlane(replicas) {
+		return nil
+	}
+	return controlPlaneAvailabilityPollers(operatorClient, replicas, 0)
+}
+
+// clusterControlPlaneReplicas returns the number of control plane replicas from the install
+// config stored in the cluster.
+func clusterControlPlaneReplicas(ctx context.Context, client kubernetes.Interface) (int, error) {
+	cm, err := client.CoreV1().ConfigMaps("kube-system").Get(ctx, "cluster-config-v1", metav1.GetOptions{})
+	if err != nil {
+		return 0, fmt.Errorf("failed to get install config from cluster: %w", err)
+	}
+	installConfig, err := installconfig.Parse([]byte(cm.Data["install-config"]))
+	if err != nil {
+		return 0, err
+	}
+	if installConfig.ControlPlane == nil || installConfig.ControlPlane.Replicas == nil {
+		return 0, fmt.Errorf("install config of the cluster has no control plane replicas")
+	}
+	return int(*installConfig.ControlPlane.Replicas), nil
+}
+
+// podStatuses returns the statuses of the required pods sorted by description.
+func podStatuses(ps map[string]*podStatus) []PodStatus {
+	statuses := make([]PodStatus, 0, len(ps))
+	for desc, s := range ps {
+		statuses = append(statuses, PodStatus{Description: desc, Status: s.String(), Ready: s.runningAndReady()})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Description < statuses[j].Description })
+	return statuses
+}
+
+// phase returns the phase given the events, pods and conditions of the status.
+func (s *BootstrapStatus) phase() BootstrapPhase {
+	sent := map[string]bool{}
+	for _, e := range s.Events {
+		sent[e.Name] = e.Sent
+	}
+	switch {
+	case sent[bootstrapFinishedEvent]:
+		return PhaseFinished
+	case sent[bootstrapSuccessEvent]:
+		return PhaseSucceeded
+	}
+	for _, p := range s.Pods {
+		if !p.Ready {
+			return PhasePodsPending
+		}
+	}
+	for _, c := range s.Conditions {
+		if !c.Satisfied {
+			return PhaseControlPlanePending
+		}
+	}
+	return PhaseControlPlaneAvailable
+}
+
+// Write writes the status to w, as JSON if asJSON is set and as text otherwise.
+func (s *BootstrapStatus) Write(w io.Writer, asJSON bool) error {
+	if asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(s)
+	}
+
+	out := fmt.Sprintf("Phase: %s\n", s.Phase)
+	for _, e := range s.Events {
+		if e.Sent {
+			out += fmt.Sprintf("\tEvent:%29s\tsent at %s\n", e.Name, e.Time.Format(time.RFC3339))
+		} else {
+			out += fmt.Sprintf("\tEvent:%29s\tnot sent\n", e.Name)
+		}
+	}
+	for _, p := range s.Pods {
+		out += fmt.Sprintf("\tPod Status:%24s\t%s\n", p.Description, p.Status)
+	}
+	for _, c := range s.Conditions {
+		state := "not satisfied"
+		if c.Satisfied {
+			state = "satisfied"
+		}
+		out += fmt.Sprintf("\tCondition %q %s, last status: %s\n", c.Condition, state, c.Reason)
+	}
+	_, err := io.WriteString(w, out)
+	return err
+}
@@ -0,0 +1,121 @@
+package start
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestBootstrapStatusPhase(t *testing.T) {
+	sent := &metav1.Time{Time: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}
+	events := func(success, finished bool) []EventStatus {
+		return []EventStatus{{Name: bootstrapSuccessEvent, Sent: success}, {Name: bootstrapFinishedEvent, Sent: finished}}
+	}
+	readyPods := podStatuses(map[string]*podStatus{
+		"kube-system/kube-apiserver": {Phase: v1.PodRunning, IsReady: true},
+	})
+	pendingPods := podStatuses(map[string]*podStatus{
+		"kube-system/kube-apiserver": {Phase: v1.PodRunning, IsReady: true},
+		"kube-system/kube-scheduler": nil,
+	})
+
+	tests := []struct {
+		name     string
+		status   BootstrapStatus
+		expected BootstrapPhase
+	}{
+		{
+			name:     "pods pending",
+			status:   BootstrapStatus{Events: events(false, false), Pods: pendingPods},
+			expected: PhasePodsPending,
+		},
+		{
+			name:     "control plane pending",
+			status:   BootstrapStatus{Events: events(false, false), Pods: readyPods, Conditions: []ConditionStatus{{Condition: "foo", Satisfied: true}, {Condition: "bar"}}},
+			expected: PhaseControlPlanePending,
+		},
+		{
+			name:     "control plane available",
+			status:   BootstrapStatus{Events: events(false, false), Pods: readyPods, Conditions: []ConditionStatus{{Condition: "foo", Satisfied: true}}},
+			expected: PhaseControlPlaneAvailable,
+		},
+		{
+			name:     "succeeded",
+			status:   BootstrapStatus{Events: events(true, false), Pods: pendingPods},
+			expected: PhaseSucceeded,
+		},
+		{
+			name:     "finished",
+			status:   BootstrapStatus{Events: []EventStatus{{Name: bootstrapSuccessEvent, Sent: true, Time: sent}, {Name: bootstrapFinishedEvent, Sent: true, Time: sent}}},
+			expected: PhaseFinished,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.status.phase(); got != tt.expected {
+				t.Errorf("expected phase %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestBootstrapStatusWrite(t *testing.T) {
+	status := &BootstrapStatus{
+		Phase: PhasePodsPending,
+		Events: []EventStatus{
+			{Name: bootstrapSuccessEvent, Sent: true, Time: &metav1.Time{Time: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}},
+			{Name: bootstrapFinishedEvent},
+		},
+		Pods: podStatuses(map[string]*podStatus{
+			"kube-system/kube-scheduler": {Phase: v1.PodRunning},
+			"kube-system/kube-apiserver": nil,
+		}),
+		Conditions: []ConditionStatus{{Condition: "foo", Reason: "bar"}},
+	}
+
+	out := &bytes.Buffer{}
+	if err := status.Write(out, false); err != nil {
+		t.Fatal(err)
+	}
+	expected := `Phase: PodsPending
+	Event:            bootstrap-success	sent at 2024-01-02T03:04:05Z
+	Event:           bootstrap-finished	not sent
+	Pod Status:kube-system/kube-apiserver	DoesNotExist
+	Pod Status:kube-system/kube-scheduler	RunningNotReady
+	Condition "foo" not satisfied, last status: bar
+`
+	if out.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, out.String())
+	}
+
+	out.Reset()
+	if err := status.Write(out, true); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(out.Bytes(), []byte(`"phase": "PodsPending"`)) || !bytes.Contains(out.Bytes(), []byte(`"status": "DoesNotExist"`)) {
+		t.Errorf("unexpected JSON status: %s", out.String())
+	}
+}
+
+func TestControlPlaneStatusPollers(t *testing.T) {
+	if pollers := controlPlaneStatusPollers(nil, 1, nil); len(pollers) != 0 {
+		t.Errorf("expected no control plane conditions for a single replica, got %d", len(pollers))
+	}
+	if pollers := controlPlaneStatusPollers(nil, 3, nil); len(pollers) != 4 {
+		t.Errorf("expected 4 control plane conditions for 3 replicas, got %d", len(pollers))
+	}
+
+	pollers := controlPlaneStatusPollers(nil, 0, errors.New("failed to get install config from cluster: forbidden"))
+	if len(pollers) != 1 {
+		t.Fatalf("expected a single condition for unknown replicas, got %d", len(pollers))
+	}
+	reason, satisfied := pollers[0].condition(context.Background())
+	if satisfied || reason != "failed to get install config from cluster: forbidden" {
+		t.Errorf("expected the condition not to be satisfied with the error as reason, got %v, %q", satisfied, reason)
+	}
+}
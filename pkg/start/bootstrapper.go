@@ -0,0 +1,276 @@
+package start
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/utils/clock"
+)
+
+// ControlPlaneLauncher starts and tears down the temporary bootstrap control plane.
+type ControlPlaneLauncher interface {
+	// Start launches the control plane and waits for its API. Teardown is called even if
+	// Start fails.
+	Start() error
+	// Teardown brings down the control plane and waits up to terminationTimeout for its API
+	// to terminate. It must be idempotent.
+	Teardown(terminationTimeout time.Duration) error
+}
+
+// PodReadinessChecker waits until the required pods are running and ready.
+type PodReadinessChecker interface {
+	WaitUntilPodsRunning(ctx context.Context) error
+}
+
+// AvailabilityGate is a condition which must be satisfied before the bootstrap-success event is
+// sent, like the availability of the self-hosted control plane.
+type AvailabilityGate interface {
+	// Name describes what is waited for, e.g. "required cluster operators".
+	Name() string
+	Wait(ctx context.Context) error
+}
+
+// AssetCreator creates the assets in the cluster of restConfig, until all are created or ctx
+// is done.
+type AssetCreator interface {
+	CreateAssets(ctx context.Context, restConfig *rest.Config) error
+}
+
+// EventSink sends the bootstrap events and waits for the tear down event of the installer.
+type EventSink interface {
+	// SendEvent sends the event ns/name. An existing event counts as sent.
+	SendEvent(ctx context.Context, ns, name string) error
+	// WaitForEvent waits until the event ns/name exists, or until ctx is done.
+	WaitForEvent(ctx context.Context, ns, name string) error
+}
+
+// Bootstrapper runs the bootstrap sequence of the start command with pluggable components: it
+// launches the bootstrap control plane, creates assets while waiting for the required pods and
+// the availability gates, sends the bootstrap-success event, creates the remaining assets,
+// tears down the bootstrap control plane and sends the bootstrap-finished event. Hooks are run
+// at the HookPhases in between.
+//
+// NewStaticPodControlPlane, NewPodReadinessChecker, NewControlPlaneAvailabilityGate,
+// NewClusterOperatorsGate, NewManifestCreator, NewEventSink and NewHooksDir return the
+// components used by the start command.
+type Bootstrapper struct {
+	// ControlPlane launches the bootstrap control plane. It may be nil, e.g. for an externally
+	// managed control plane.
+	ControlPlane ControlPlaneLauncher
+	Pods         PodReadinessChecker
+	// Gates are waited for one after the other once the required pods are running.
+	Gates  []AvailabilityGate
+	Assets AssetCreator
+	Events EventSink
+	// Clock defaults to the real clock.
+	Clock clock.Clock
+	// Hooks is optional. The teardown hooks are only run if there is a ControlPlane.
+	Hooks PhaseHooks
+
+	// LoopbackRestConfig is the config of the bootstrap control plane, which assets are
+	// created with until the bootstrap-success event is sent, and afterwards unless
+	// EarlyTearDown is set.
+	LoopbackRestConfig *rest.Config
+	// RestConfig is the config of the cluster, which the remaining assets are created with if
+	// EarlyTearDown is set.
+	RestConfig *rest.Config
+
+	// EarlyTearDown tears down the bootstrap control plane before the remaining assets are
+	// created.
+	EarlyTearDown      bool
+	TerminationTimeout time.Duration
+	// TearDownDelay is waited for before the bootstrap-success event is sent, to give load
+	// balancers time to observe the self-hosted control plane.
+	TearDownDelay        time.Duration
+	AssetsCreatedTimeout time.Duration
+	// WaitForTearDownEvent if set is an event of the form <ns>/<event-name> which is waited
+	// for before the bootstrap control plane is torn down.
+	WaitForTearDownEvent string
+}
+
+// Run runs the bootstrap sequence.
+func (b *Bootstrapper) Run() error {
+	if b.Pods == nil || b.Assets == nil || b.Events == nil {
+		return errors.New("bootstrapper requires a pod readiness checker, an asset creator and an event sink")
+	}
+	clk := b.Clock
+	if clk == nil {
+		clk = clock.RealClock{}
+	}
+	controlPlane := b.ControlPlane
+
+	// tearDown tears down the bootstrap control plane between the teardown hooks. It is torn
+	// down even if the before-teardown hooks fail. Errors of the tear down itself are reported
+	// only, while those of the hooks are returned. Set controlPlane to nil to avoid a second
+	// tear down in the defer func.
+	tearDown := func() error {
+		hooksErr := b.runHooks(HookBeforeTeardown)
+		err := controlPlane.Teardown(b.TerminationTimeout)
+		controlPlane = nil
+		if err != nil {
+			UserOutput("Error tearing down temporary bootstrap control plane: %v\n", err)
+		}
+		if hooksErr != nil {
+			return hooksErr
+		}
+		return b.runHooks(HookAfterTeardown)
+	}
+
+	// Always tear down the bootstrap control plane and clean up manifests and secrets.
+	defer func() {
+		if controlPlane == nil {
+			return
+		}
+		if err := tearDown(); err != nil {
+			UserOutput("Error: %v\n", err)
+		}
+	}()
+
+	var err error
+	defer func() {
+		// Always report errors.
+		if err != nil {
+			UserOutput("Error: %v\n", err)
+		}
+	}()
+
+	if err = b.runHooks(HookBeforeControlPlaneStart); err != nil {
+		return err
+	}
+	if controlPlane != nil {
+		if err = controlPlane.Start(); err != nil {
+			return err
+		}
+	}
+	if err = b.runHooks(HookAfterAPIUp); err != nil {
+		return err
+	}
+
+	// create assets (in the background) and wait for control plane to be up
+	createAssetsInBackground := func(ctx context.Context, cancel func(), restConfig *rest.Config) *sync.WaitGroup {
+		done := sync.WaitGroup{}
+		done.Add(1)
+		go func() {
+			defer done.Done()
+			if err := b.Assets.CreateAssets(ctx, restConfig); err != nil {
+				select {
+				case <-ctx.Done():
+				default:
+					UserOutput("Assert creation failed: %v\n", err)
+					cancel()
+				}
+			}
+		}()
+		return &done
+	}
+	ctx, cancel := context.WithTimeout(context.TODO(), bootstrapPodsRunningTimeout)
+	defer cancel()
+	assetsDone := createAssetsInBackground(ctx, cancel, b.LoopbackRestConfig)
+	if err = b.Pods.WaitUntilPodsRunning(ctx); err != nil {
+		return err
+	}
+	if err = b.runHooks(HookAfterPodsRunning); err != nil {
+		return err
+	}
+
+	// the gates have their own timeouts
+	for _, gate := range b.Gates {
+		UserOutput("Waiting for %s\n", gate.Name())
+		if err = gate.Wait(context.Background()); err != nil {
+			return err
+		}
+	}
+
+	// if we are here, self hosted control plane is available
+	if b.TearDownDelay > 0 {
+		UserOutput("Waiting %v to give load-balancers time to observe the self-hosted control-plane\n", b.TearDownDelay)
+		clk.Sleep(b.TearDownDelay)
+	}
+
+	cancel()
+	assetsDone.Wait()
+
+	if err = b.runHooks(HookBeforeBootstrapSuccess); err != nil {
+		return err
+	}
+
+	// notify installer that we are ready to tear down the temporary bootstrap control plane
+	UserOutput("Sending bootstrap-success event.\n")
+	if err := b.Events.SendEvent(context.Background(), bootstrapEventNamespace, bootstrapSuccessEvent); err != nil {
+		return err
+	}
+
+	// continue with assets
+	ctx, cancel = context.WithTimeout(context.Background(), b.AssetsCreatedTimeout)
+	defer cancel()
+	if b.EarlyTearDown {
+		// switch over to ELB client and continue with the assets
+		assetsDone = createAssetsInBackground(ctx, cancel, b.RestConfig)
+	} else {
+		// we don't tear down the local control plane early. So we can keep using it and enjoy the speed up.
+		assetsDone = createAssetsInBackground(ctx, cancel, b.LoopbackRestConfig)
+	}
+
+	// optionally wait for tear down event coming from the installer. This is necessary to
+	// remove the bootstrap node from the AWS load balancer.
+	if len(b.WaitForTearDownEvent) != 0 {
+		ss := strings.Split(b.WaitForTearDownEvent, "/")
+		if len(ss) != 2 {
+			return fmt.Errorf("tear down event name of format <namespace>/<event-name> expected, got: %q", b.WaitForTearDownEvent)
+		}
+		ns, name := ss[0], ss[1]
+		if err := b.Events.WaitForEvent(context.TODO(), ns, name); err != nil {
+			return err
+		}
+		UserOutput("Got %s event.\n", b.WaitForTearDownEvent)
+	}
+
+	// tear down the bootstrap control plane early.
+	// TODO: tear down early is probably not meaningful, we can tear down
+	// only when the self hosted control plane is available, we should remove
+	// this command line option. Maybe it can only apply to SNO only?
+	// currently it is set to false by bootkube.sh
+	if b.EarlyTearDown && controlPlane != nil {
+		if err = tearDown(); err != nil {
+			return err
+		}
+	}
+
+	// wait for the tail of assets to be created after tear down
+	UserOutput("Waiting for remaining assets to be created.\n")
+	assetsDone.Wait()
+	// We want to fail in case we failed to create some manifests
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("timed out creating manifests")
+	}
+
+	UserOutput("Sending bootstrap-finished event.\n")
+	// client actually refers to localhost, so if we want to create
+	// an event, that has to be before the tear down starts.
+	// TODO: this should move to bootkube.sh
+	if err := b.Events.SendEvent(context.Background(), bootstrapEventNamespace, bootstrapFinishedEvent); err != nil {
+		return err
+	}
+
+	// tear down the bootstrap control plane late after asset creation.
+	if !b.EarlyTearDown && controlPlane != nil {
+		if err = tearDown(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// runHooks runs the hooks of the phase, if any.
+func (b *Bootstrapper) runHooks(phase HookPhase) error {
+	if b.Hooks == nil {
+		return nil
+	}
+	return b.Hooks.RunHooks(context.Background(), phase)
+}
@@ -0,0 +1,156 @@
+package start
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/rest"
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+// fakeComponents records the calls of all Bootstrapper components.
+type fakeComponents struct {
+	lock       sync.Mutex
+	steps      []string
+	assetHosts []string
+	gateErr    error
+}
+
+func (f *fakeComponents) record(step string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.steps = append(f.steps, step)
+}
+
+func (f *fakeComponents) Start() error {
+	f.record("start")
+	return nil
+}
+
+func (f *fakeComponents) Teardown(time.Duration) error {
+	f.record("teardown")
+	return nil
+}
+
+func (f *fakeComponents) WaitUntilPodsRunning(context.Context) error {
+	f.record("pods")
+	return nil
+}
+
+func (f *fakeComponents) Name() string {
+	return "gate"
+}
+
+func (f *fakeComponents) Wait(context.Context) error {
+	f.record("gate")
+	return f.gateErr
+}
+
+func (f *fakeComponents) CreateAssets(ctx context.Context, restConfig *rest.Config) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.assetHosts = append(f.assetHosts, restConfig.Host)
+	return nil
+}
+
+func (f *fakeComponents) SendEvent(ctx context.Context, ns, name string) error {
+	f.record("send " + ns + "/" + name)
+	return nil
+}
+
+func (f *fakeComponents) WaitForEvent(ctx context.Context, ns, name string) error {
+	f.record("wait " + ns + "/" + name)
+	return nil
+}
+
+func (f *fakeComponents) RunHooks(ctx context.Context, phase HookPhase) error {
+	f.record("hooks " + string(phase))
+	return nil
+}
+
+func TestBootstrapperRun(t *testing.T) {
+	tests := []struct {
+		name                 string
+		earlyTearDown        bool
+		waitForTearDownEvent string
+		hooks                bool
+		gateErr              error
+		expectedSteps        []string
+		expectedAssetHosts   []string
+		expectErr            bool
+	}{
+		{
+			name:               "late tear down",
+			expectedSteps:      []string{"start", "pods", "gate", "send kube-system/bootstrap-success", "send kube-system/bootstrap-finished", "teardown"},
+			expectedAssetHosts: []string{"localhost:6443", "localhost:6443"},
+		},
+		{
+			name:                 "early tear down after tear down event",
+			earlyTearDown:        true,
+			waitForTearDownEvent: "kube-system/bootstrap-teardown",
+			expectedSteps:        []string{"start", "pods", "gate", "send kube-system/bootstrap-success", "wait kube-system/bootstrap-teardown", "teardown", "send kube-system/bootstrap-finished"},
+			expectedAssetHosts:   []string{"localhost:6443", "api.example.com:6443"},
+		},
+		{
+			name:  "hooks",
+			hooks: true,
+			expectedSteps: []string{
+				"hooks before-control-plane-start", "start", "hooks after-api-up", "pods", "hooks after-pods-running", "gate",
+				"hooks before-bootstrap-success", "send kube-system/bootstrap-success", "send kube-system/bootstrap-finished",
+				"hooks before-teardown", "teardown", "hooks after-teardown",
+			},
+			expectedAssetHosts: []string{"localhost:6443", "localhost:6443"},
+		},
+		{
+			name:          "gate failure",
+			gateErr:       errors.New("gate failed"),
+			expectedSteps: []string{"start", "pods", "gate", "teardown"},
+			expectErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeComponents{gateErr: tt.gateErr}
+			clk := testingclock.NewFakeClock(time.Now())
+			start := clk.Now()
+			b := &Bootstrapper{
+				ControlPlane:         fake,
+				Pods:                 fake,
+				Gates:                []AvailabilityGate{fake},
+				Assets:               fake,
+				Events:               fake,
+				Clock:                clk,
+				LoopbackRestConfig:   &rest.Config{Host: "localhost:6443"},
+				RestConfig:           &rest.Config{Host: "api.example.com:6443"},
+				EarlyTearDown:        tt.earlyTearDown,
+				TearDownDelay:        time.Minute,
+				AssetsCreatedTimeout: time.Minute,
+				WaitForTearDownEvent: tt.waitForTearDownEvent,
+			}
+			if tt.hooks {
+				b.Hooks = fake
+			}
+			err := b.Run()
+			if tt.expectErr != (err != nil) {
+				t.Fatalf("expected error %v, got %v", tt.expectErr, err)
+			}
+			if !reflect.DeepEqual(fake.steps, tt.expectedSteps) {
+				t.Errorf("expected steps %v, got %v", tt.expectedSteps, fake.steps)
+			}
+			if tt.expectErr {
+				// asset creation is not waited for on errors
+				return
+			}
+			if !reflect.DeepEqual(fake.assetHosts, tt.expectedAssetHosts) {
+				t.Errorf("expected assets to be created against %v, got %v", tt.expectedAssetHosts, fake.assetHosts)
+			}
+			if clk.Since(start) != time.Minute {
+				t.Errorf("expected the tear down delay to be slept on the clock, got %v", clk.Since(start))
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package start
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	configv1 "github.com/openshift/api/config/v1"
+	configversionedclient "github.com/openshift/client-go/config/clientset/versioned"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ClusterOperatorRequirement names a ClusterOperator and the condition states it has to
+// report before the bootstrap-success event is sent.
+type ClusterOperatorRequirement struct {
+	Name       string
+	Conditions map[configv1.ClusterStatusConditionType]configv1.ConditionStatus
+}
+
+// DefaultClusterOperatorConditions are the condition states required from a ClusterOperator
+// if none are given explicitly.
+func DefaultClusterOperatorConditions() map[configv1.ClusterStatusConditionType]configv1.ConditionStatus {
+	return map[configv1.ClusterStatusConditionType]configv1.ConditionStatus{
+		configv1.OperatorAvailable: configv1.ConditionTrue,
+		configv1.OperatorDegraded:  configv1.ConditionFalse,
+	}
+}
+
+// NewClusterOperatorsGate returns the gate which waits until every required ClusterOperator
+// reports the required condition states.
+func NewClusterOperatorsGate(loopbackConfigClient configversionedclient.Interface, requirements []ClusterOperatorRequirement, timeout time.Duration) AvailabilityGate {
+	return &pollerGate{
+		name:    "required cluster operators",
+		pollers: clusterOperatorPollers(loopbackConfigClient, requirements, timeout),
+	}
+}
+
+func clusterOperatorPollers(loopbackConfigClient configversionedclient.Interface, requirements []ClusterOperatorRequirement, timeout time.Duration) []*poller {
+	pollers := make([]*poller, 0, len(requirements))
+	for _, r := range requirements {
+		pollers = append(pollers, newClusterOperatorPoller(loopbackConfigClient, r, timeout))
+	}
+	return pollers
+}
+
+func newClusterOperatorPoller(loopbackConfigClient configversionedclient.Interface, requirement ClusterOperatorRequirement, timeout time.Duration) *poller {
+	return &poller{
+		timeout: timeout,
+		what:    fmt.Sprintf("clusteroperator/%s should be %s", requirement.Name, formatConditions(requirement.Conditions)),
+		condition: func(ctx context.Context) (string, bool) {
+			client := loopbackConfigClient.ConfigV1().ClusterOperators()
+			co, err := client.Get(ctx, requirement.Name, metav1.GetOptions{})
+			if err != nil {
+				return fmt.Sprintf("error getting clusteroperators/%s - %v", requirement.Name, err), false
+			}
+			return clusterOperatorStatus(co, requirement.Conditions)
+		},
+	}
+}
+
+// clusterOperatorStatus compares the conditions of the given ClusterOperator with the
+// required ones, and reports reason and message of every condition not matching.
+func clusterOperatorStatus(co *configv1.ClusterOperator, required map[configv1.ClusterStatusConditionType]configv1.ConditionStatus) (string, bool) {
+	conditions := map[configv1.ClusterStatusConditionType]configv1.ClusterOperatorStatusCondition{}
+	for _, c := range co.Status.Conditions {
+		conditions[c.Type] = c
+	}
+
+	msg := ""
+	satisfied := true
+	for _, t := range sortedConditionTypes(required) {
+		c, ok := conditions[t]
+		switch {
+		case !ok:
+			msg = fmt.Sprintf("%s [%s is missing, want %s]", msg, t, required[t])
+			satisfied = false
+		case c.Status != required[t]:
+			msg = fmt.Sprintf("%s [%s=%s, want %s, reason: %s, message: %s]", msg, t, c.Status, required[t], c.Reason, c.Message)
+			satisfied = false
+		default:
+			msg = fmt.Sprintf("%s [%s=%s]", msg, t, c.Status)
+		}
+	}
+	return fmt.Sprintf("clusteroperators/%s conditions: %s", co.Name, msg), satisfied
+}
+
+func formatConditions(conditions map[configv1.ClusterStatusConditionType]configv1.ConditionStatus) string {
+	ss := make([]string, 0, len(conditions))
+	for _, t := range sortedConditionTypes(conditions) {
+		ss = append(ss, fmt.Sprintf("%s=%s", t, conditions[t]))
+	}
+	return strings.Join(ss, ", ")
+}
+
+func sortedConditionTypes(conditions map[configv1.ClusterStatusConditionType]configv1.ConditionStatus) []configv1.ClusterStatusConditionType {
+	types := make([]configv1.ClusterStatusConditionType, 0, len(conditions))
+	for t := range conditions {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return types
+}
@@ -0,0 +1,66 @@
+package start
+
+import (
+	"strings"
+	"testing"
+
+	configv1 "github.com/openshift/api/config/v1"
+)
+
+func TestClusterOperatorStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		conditions []configv1.ClusterOperatorStatusCondition
+		required   map[configv1.ClusterStatusConditionType]configv1.ConditionStatus
+		satisfied  bool
+		wantReason string
+	}{
+		{
+			name: "all conditions match",
+			conditions: []configv1.ClusterOperatorStatusCondition{
+				{Type: configv1.OperatorAvailable, Status: configv1.ConditionTrue},
+				{Type: configv1.OperatorDegraded, Status: configv1.ConditionFalse},
+			},
+			required:  DefaultClusterOperatorConditions(),
+			satisfied: true,
+		},
+		{
+			name: "condition missing",
+			conditions: []configv1.ClusterOperatorStatusCondition{
+				{Type: configv1.OperatorAvailable, Status: configv1.ConditionTrue},
+			},
+			required:   DefaultClusterOperatorConditions(),
+			satisfied:  false,
+			wantReason: "Degraded is missing",
+		},
+		{
+			name: "condition mismatch",
+			conditions: []configv1.ClusterOperatorStatusCondition{
+				{Type: configv1.OperatorAvailable, Status: configv1.ConditionTrue},
+				{Type: configv1.OperatorDegraded, Status: configv1.ConditionFalse},
+				{Type: configv1.OperatorProgressing, Status: configv1.ConditionTrue, Reason: "NodeInstaller", Message: "3 nodes are at revision 2"},
+			},
+			required: map[configv1.ClusterStatusConditionType]configv1.ConditionStatus{
+				configv1.OperatorAvailable:   configv1.ConditionTrue,
+				configv1.OperatorProgressing: configv1.ConditionFalse,
+			},
+			satisfied:  false,
+			wantReason: "reason: NodeInstaller, message: 3 nodes are at revision 2",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			co := &configv1.ClusterOperator{}
+			co.Name = "etcd"
+			co.Status.Conditions = test.conditions
+
+			reason, satisfied := clusterOperatorStatus(co, test.required)
+			if satisfied != test.satisfied {
+				t.Errorf("expected satisfied=%v, got %v: %s", test.satisfied, satisfied, reason)
+			}
+			if !strings.Contains(reason, test.wantReason) {
+				t.Errorf("expected reason to contain %q, got: %s", test.wantReason, reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package start
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/openshift/cluster-bootstrap/pkg/create"
+)
+
+// ErrAssetsNotCreated is wrapped by the error of CreateAssets if creation attempts ran, but not
+// all manifests could be created before the context was done, or in strict mode. Errors loading
+// the manifests or building the clients do not wrap it.
+var ErrAssetsNotCreated = errors.New("not all manifests were created")
+
+// CreateAssetsConfig configures CreateAssets.
+type CreateAssetsConfig struct {
+	// Kubeconfig is the path to the kubeconfig of the cluster to create the manifests in.
+	Kubeconfig string
+	// ManifestDir is the directory of the manifests to create.
+	ManifestDir          string
+	ManifestOverrideDirs []string
+	ManifestSelection    create.Selection
+	// ClusterConfig is the path to the cluster-config ConfigMap manifest holding the install
+	// config. If set, manifests of other feature sets and of disabled capabilities are skipped.
+	ClusterConfig string
+	// Strict stops retrying as soon as a creation attempt fails, see create.CreateOptions.
+	Strict bool
+	// ReportFile if set is where the asset creation report is written to.
+	ReportFile string
+}
+
+// CreateAssets creates the manifests in the cluster with the same engine as the start command,
+// retrying until all are created or the context is done. There is no bootstrap control plane
+// involved, so that it works against any cluster, e.g. to create the remaining manifests after a
+// failed bootstrap.
+func CreateAssets(ctx context.Context, config CreateAssetsConfig) error {
+	restConfig, err := clientcmd.BuildConfigFromFlags("", config.Kubeconfig)
+	if err != nil {
+		return err
+	}
+
+	var manifestFilters []create.ManifestPredicate
+	if len(config.ClusterConfig) > 0 {
+		if manifestFilters, err = installConfigManifestFilters(config.ClusterConfig); err != nil {
+			return err
+		}
+	}
+	if !config.ManifestSelection.Empty() {
+		UserOutput("Creating %s\n", config.ManifestSelection)
+		manifestFilters = append(manifestFilters, config.ManifestSelection.ManifestFilter())
+	}
+	if len(config.ManifestOverrideDirs) > 0 {
+		UserOutput("Overriding manifests with those of %s, in increasing order of precedence\n", strings.Join(config.ManifestOverrideDirs, ", "))
+	}
+
+	tracker := create.NewTracker()
+	defer func() {
+		UserOutput("Asset creation summary:\n%s", tracker.Summary())
+		if len(config.ReportFile) == 0 {
+			return
+		}
+		if err := tracker.WriteReport(config.ReportFile); err != nil {
+			UserOutput("Error writing asset creation report: %v\n", err)
+		}
+	}()
+
+	creator := NewManifestCreator(config.ManifestDir, create.CreateOptions{
+		Verbose:         true,
+		StdErr:          os.Stderr,
+		PathFilters:     []create.PathPredicate{config.ManifestSelection.PathFilter()},
+		ManifestFilters: manifestFilters,
+		OverrideDirs:    config.ManifestOverrideDirs,
+		Tracker:         tracker,
+		Strict:          config.Strict,
+	})
+	err = creator.CreateAssets(ctx, restConfig)
+	var notCreated *create.NotCreatedError
+	if errors.As(err, &notCreated) {
+		return fmt.Errorf("%w: %v", ErrAssetsNotCreated, err)
+	}
+	return err
+}
+
+// NewManifestCreator returns the AssetCreator of the start command, which creates the manifests
+// of manifestDir. If options has a tracker, the number of created manifests is printed
+// periodically.
+func NewManifestCreator(manifestDir string, options create.CreateOptions) AssetCreator {
+	return &manifestCreator{manifestDir: manifestDir, options: options}
+}
+
+type manifestCreator struct {
+	manifestDir string
+	options     create.CreateOptions
+}
+
+func (c *manifestCreator) CreateAssets(ctx context.Context, restConfig *rest.Config) error {
+	if c.options.Tracker != nil {
+		stopProgress := make(chan struct{})
+		defer close(stopProgress)
+		go reportAssetProgress(c.options.Tracker, stopProgress)
+	}
+	return create.EnsureManifestsCreated(ctx, c.manifestDir, restConfig, c.options)
+}
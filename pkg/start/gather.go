@@ -0,0 +1,378 @@
+package start
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+	operatorversionedclient "github.com/openshift/client-go/operator/clientset/versioned"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/openshift/cluster-bootstrap/pkg/installconfig"
+	"github.com/openshift/cluster-bootstrap/pkg/redact"
+)
+
+// gatherDir is the directory of the gather archive holding all files.
+const gatherDir = "bootstrap-gather"
+
+// GatherConfig configures Gather.
+type GatherConfig struct {
+	AssetDir        string
+	PodManifestPath string
+	// Kubeconfig is the kubeconfig of the apiserver to gather from. It defaults to the loopback
+	// kubeconfig of the asset dir.
+	Kubeconfig string
+	// External talks to the server of the kubeconfig, instead of the local apiserver.
+	External bool
+	// RequiredPodPrefixes are the pods whose statuses are gathered, as for Config.
+	RequiredPodPrefixes map[string][]string
+	// AssetCreationReport is the asset creation report file. It defaults to the one of the
+	// asset dir.
+	AssetCreationReport string
+	// ConfigFiles are additional rendered config files to gather, e.g. Ignition configs.
+	ConfigFiles []string
+	// Output is the path of the gzip compressed tar archive to write.
+	Output string
+}
+
+// assetFile is an entry of the asset dir layout.
+type assetFile struct {
+	Path string      `json:"path"`
+	Mode os.FileMode `json:"mode"`
+	Size int64       `json:"size"`
+}
+
+// ownedManifest is a static pod manifest of the bootstrap control plane, and whether the copy
+// in the pod manifest path is Present, Missing or Modified.
+type ownedManifest struct {
+	Manifest string `json:"manifest"`
+	Path     string `json:"path"`
+	State    string `json:"state"`
+}
+
+// requiredPod is the status of a pod matching the required pod prefixes.
+type requiredPod struct {
+	Description string           `json:"description"`
+	Namespace   string           `json:"namespace"`
+	Name        string           `json:"name"`
+	Status      corev1.PodStatus `json:"status"`
+}
+
+// operatorNodeStatuses are the NodeStatuses and conditions of an operator checked by the
+// control plane availability pollers.
+type operatorNodeStatuses struct {
+	Resource     string                         `json:"resource"`
+	NodeStatuses []operatorv1.NodeStatus        `json:"nodeStatuses,omitempty"`
+	Conditions   []operatorv1.OperatorCondition `json:"conditions,omitempty"`
+	Error        string                         `json:"error,omitempty"`
+}
+
+// Gather writes a diagnostics archive of a bootstrap: the asset dir layout without any
+// contents, the ownership of the copied static pod manifests, the asset creation report and the
+// rendered configs, and, if the apiserver can be reached, the statuses of the required pods,
+// the events of kube-system and the openshift-* namespaces and the operator NodeStatuses. Every
+// part is gathered on a best-effort basis, failures are recorded in errors.txt of the archive.
+// Private keys, credentials, the pull secret and the contents of the TLS and auth assets are
+// redacted, also from the base64 encoded or gzip compressed files of Ignition configs. Config
+// files are stored by base name, numbered if several have the same.
+func Gather(ctx context.Context, config GatherConfig) error {
+	redactor, err := redact.ForAssetDir(config.AssetDir)
+	if err != nil {
+		return fmt.Errorf("failed to read TLS and auth assets to redact: %w", err)
+	}
+	if installConfig, err := installconfig.Load(filepath.Join(config.AssetDir, assetPathClusterConfig)); err == nil {
+		redactor.AddSecret(installConfig.PullSecret)
+	}
+	reportFile := config.AssetCreationReport
+	if len(reportFile) == 0 {
+		reportFile = filepath.Join(config.AssetDir, assetPathCreationReport)
+	}
+
+	files := map[string][]byte{}
+	var errs []string
+	addJSON := func(name string, v interface{}, err error) {
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", name, err))
+			return
+		}
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", name, err))
+			return
+		}
+		files[name] = append(data, '\n')
+	}
+	addFile := func(name, path string) {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", name, err))
+			return
+		}
+		files[name] = data
+	}
+	// config files may be Ignition configs, whose file contents are encoded. They are stored by
+	// base name, suffixed with a number if taken by an earlier config file.
+	configNames := map[string]bool{}
+	addConfigFile := func(path string) {
+		base := filepath.Base(path)
+		ext := filepath.Ext(base)
+		name := filepath.Join("config", base)
+		for n := 1; configNames[name]; n++ {
+			name = filepath.Join("config", fmt.Sprintf("%s-%d%s", strings.TrimSuffix(base, ext), n, ext))
+		}
+		configNames[name] = true
+		addFile(name, path)
+		if data, ok := files[name]; ok {
+			files[name] = redactor.Ignition(data)
+		}
+	}
+
+	layout, err := assetDirLayout(config.AssetDir)
+	addJSON("asset-dir.json", layout, err)
+	owned, err := ownedStaticManifests(config.AssetDir, config.PodManifestPath)
+	addJSON("owned-static-manifests.json", owned, err)
+	addFile(assetPathCreationReport, reportFile)
+	addConfigFile(filepath.Join(config.AssetDir, assetPathClusterConfig))
+	for _, f := range config.ConfigFiles {
+		addConfigFile(f)
+	}
+
+	if restConfig, err := gatherRestConfig(config); err != nil {
+		errs = append(errs, fmt.Sprintf("kubeconfig: %v", err))
+	} else if client, err := kubernetes.NewForConfig(restConfig); err != nil {
+		errs = append(errs, fmt.Sprintf("client: %v", err))
+	} else if operatorClient, err := operatorversionedclient.NewForConfig(restConfig); err != nil {
+		errs = append(errs, fmt.Sprintf("operator client: %v", err))
+	} else {
+		pods, err := requiredPods(ctx, client, config.RequiredPodPrefixes)
+		addJSON("pods.json", pods, err)
+		events, err := bootstrapEvents(ctx, client)
+		addJSON("events.json", events, err)
+		addJSON("operator-node-statuses.json", operatorStatuses(ctx, operatorClient), nil)
+	}
+
+	if len(errs) > 0 {
+		files["errors.txt"] = []byte(strings.Join(errs, "\n") + "\n")
+	}
+	if err := writeGatherArchive(config.Output, files, redactor); err != nil {
+		return fmt.Errorf("failed to write %s: %w", config.Output, err)
+	}
+	UserOutput("Gathered %d files to %s\n", len(files), config.Output)
+	return nil
+}
+
+// gatherRestConfig returns the config of the apiserver to gather from.
+func gatherRestConfig(config GatherConfig) (*rest.Config, error) {
+	kubeconfig := config.Kubeconfig
+	if len(kubeconfig) == 0 {
+		kubeconfig = filepath.Join(config.AssetDir, assetPathAdminKubeConfig)
+	}
+	restConfig, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	if config.External {
+		return restConfig, nil
+	}
+
+	// Set the ServerName to the original hostname so we pass the certificate check.
+	hostURL, err := url.Parse(restConfig.Host)
+	if err != nil {
+		return nil, err
+	}
+	restConfig.ServerName = hostURL.Hostname()
+	restConfig.Host = "localhost:6443"
+	return restConfig, nil
+}
+
+// assetDirLayout returns the files and directories of the asset dir, without their contents.
+func assetDirLayout(assetDir string) ([]assetFile, error) {
+	var layout []assetFile
+	err := filepath.Walk(assetDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(assetDir, path)
+		if err != nil {
+			return err
+		}
+		layout = append(layout, assetFile{Path: filepath.ToSlash(rel), Mode: info.Mode(), Size: info.Size()})
+		return nil
+	})
+	return layout, err
+}
+
+// ownedStaticManifests returns the static pod manifests of the bootstrap control plane, i.e.
+// the bootstrap manifests of the asset dir, and the state of their copies in the pod
+// manifest path.
+func ownedStaticManifests(assetDir, podManifestPath string) ([]ownedManifest, error) {
+	manifestsDir := filepath.Join(assetDir, assetPathBootstrapManifests)
+	var owned []ownedManifest
+	err := filepath.Walk(manifestsDir, func(src string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(manifestsDir, src)
+		if err != nil {
+			return err
+		}
+		m := ownedManifest{Manifest: filepath.ToSlash(rel), Path: filepath.Join(podManifestPath, rel), State: "Present"}
+		expected, err := ioutil.ReadFile(src)
+		if err != nil {
+			return err
+		}
+		actual, err := ioutil.ReadFile(m.Path)
+		switch {
+		case os.IsNotExist(err):
+			m.State = "Missing"
+		case err != nil:
+			return err
+		case !bytes.Equal(expected, actual):
+			m.State = "Modified"
+		}
+		owned = append(owned, m)
+		return nil
+	})
+	return owned, err
+}
+
+// requiredPods returns the pods matching any of the required pod prefixes.
+func requiredPods(ctx context.Context, client kubernetes.Interface, podPrefixes map[string][]string) ([]requiredPod, error) {
+	pods, err := client.CoreV1().Pods("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var required []requiredPod
+	for _, p := range pods.Items {
+		key := p.Namespace + "/" + p.Name
+		for desc, prefixes := range podPrefixes {
+			for _, prefix := range prefixes {
+				if strings.HasPrefix(key, prefix) {
+					required = append(required, requiredPod{Description: desc, Namespace: p.Namespace, Name: p.Name, Status: p.Status})
+					break
+				}
+			}
+		}
+	}
+	sort.Slice(required, func(i, j int) bool {
+		if required[i].Description != required[j].Description {
+			return required[i].Description < required[j].Description
+		}
+		return required[i].Name < required[j].Name
+	})
+	return required, nil
+}
+
+// bootstrapEvents returns the events of kube-system and the openshift-* namespaces, in the
+// order they were last seen.
+func bootstrapEvents(ctx context.Context, client kubernetes.Interface) ([]corev1.Event, error) {
+	namespaces, err := client.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var events []corev1.Event
+	for _, ns := range namespaces.Items {
+		if ns.Name != bootstrapEventNamespace && !strings.HasPrefix(ns.Name, "openshift-") {
+			continue
+		}
+		list, err := client.CoreV1().Events(ns.Name).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return events, fmt.Errorf("namespace %s: %w", ns.Name, err)
+		}
+		events = append(events, list.Items...)
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].LastTimestamp.Before(&events[j].LastTimestamp) })
+	return events, nil
+}
+
+// operatorStatuses returns the NodeStatuses and conditions of the operators whose availability
+// is checked before the bootstrap control plane is torn down.
+func operatorStatuses(ctx context.Context, client operatorversionedclient.Interface) []operatorNodeStatuses {
+	var statuses []operatorNodeStatuses
+	add := func(resource string, status operatorv1.StaticPodOperatorStatus, err error) {
+		s := operatorNodeStatuses{Resource: resource, NodeStatuses: status.NodeStatuses, Conditions: status.Conditions}
+		if err != nil {
+			s.Error = err.Error()
+		}
+		statuses = append(statuses, s)
+	}
+
+	if kas, err := client.OperatorV1().KubeAPIServers().Get(ctx, "cluster", metav1.GetOptions{}); err != nil {
+		add("kubeapiservers/cluster", operatorv1.StaticPodOperatorStatus{}, err)
+	} else {
+		add("kubeapiservers/cluster", kas.Status.StaticPodOperatorStatus, nil)
+	}
+	if ks, err := client.OperatorV1().KubeSchedulers().Get(ctx, "cluster", metav1.GetOptions{}); err != nil {
+		add("kubeschedulers/cluster", operatorv1.StaticPodOperatorStatus{}, err)
+	} else {
+		add("kubeschedulers/cluster", ks.Status.StaticPodOperatorStatus, nil)
+	}
+	if kcm, err := client.OperatorV1().KubeControllerManagers().Get(ctx, "cluster", metav1.GetOptions{}); err != nil {
+		add("kubecontrollermanagers/cluster", operatorv1.StaticPodOperatorStatus{}, err)
+	} else {
+		add("kubecontrollermanagers/cluster", kcm.Status.StaticPodOperatorStatus, nil)
+	}
+	if etcd, err := client.OperatorV1().Etcds().Get(ctx, "cluster", metav1.GetOptions{}); err != nil {
+		add("etcds/cluster", operatorv1.StaticPodOperatorStatus{}, err)
+	} else {
+		add("etcds/cluster", etcd.Status.StaticPodOperatorStatus, nil)
+	}
+	return statuses
+}
+
+// writeGatherArchive writes the redacted files to a gzip compressed tar archive, below
+// gatherDir and sorted by name.
+func writeGatherArchive(output string, files map[string][]byte, redactor *redact.Redactor) error {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	f, err := os.OpenFile(output, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	now := time.Now()
+	for _, name := range names {
+		data := redactor.Bytes(files[name])
+		hdr := &tar.Header{
+			Name:    filepath.ToSlash(filepath.Join(gatherDir, name)),
+			Mode:    0600,
+			Size:    int64(len(data)),
+			ModTime: now,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,227 @@
+package start
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGather(t *testing.T) {
+	assetDir, podManifestPath := t.TempDir(), t.TempDir()
+	kubeconfig := `apiVersion: v1
+kind: Config
+clusters:
+- name: cluster
+  cluster:
+    server: https://127.0.0.1:1
+contexts:
+- name: admin
+  context:
+    cluster: cluster
+    user: admin
+current-context: admin
+users:
+- name: admin
+  user:
+    token: secret-token
+`
+	for path, content := range map[string]string{
+		"tls/service-account.key":            "secret-service-account-key\n",
+		"auth/kubeconfig-loopback":           kubeconfig,
+		"bootstrap-manifests/apiserver.yaml": "apiserver",
+		"bootstrap-manifests/etcd.yaml":      "etcd",
+		"bootstrap-manifests/scheduler.yaml": "scheduler",
+		assetPathClusterConfig:               "data:\n  install-config: |\n    pullSecret: '{\"auths\":{\"quay.io\":{\"auth\":\"dXNlcjpwYXNz\"}}}'\n",
+		assetPathCreationReport:              `{"total": 1}`,
+	} {
+		writeTestFile(t, filepath.Join(assetDir, path), content)
+	}
+	writeTestFile(t, filepath.Join(podManifestPath, "apiserver.yaml"), "apiserver")
+	writeTestFile(t, filepath.Join(podManifestPath, "etcd.yaml"), "modified")
+	// the pull secret and the kubeconfig are embedded base64 encoded and gzip compressed
+	pullSecret := base64.StdEncoding.EncodeToString([]byte(`{"auths":{"quay.io":{"auth":"dXNlcjpwYXNz"}}}`))
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	if _, err := zw.Write([]byte(kubeconfig)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	compressedKubeconfig := base64.StdEncoding.EncodeToString(compressed.Bytes())
+	configDir := t.TempDir()
+	ignition := filepath.Join(configDir, "master.ign")
+	writeTestFile(t, ignition, `{"contents":"data:,secret-service-account-key","pullSecret":"`+pullSecret+`"}`)
+	bootstrapIgnition := filepath.Join(configDir, "bootstrap.ign")
+	writeTestFile(t, bootstrapIgnition, `{"ignition":{"version":"3.2.0"},"storage":{"files":[
+{"path":"/var/lib/kubelet/config.json","contents":{"source":"data:text/plain;charset=utf-8;base64,`+pullSecret+`"}},
+{"path":"/opt/openshift/auth/kubeconfig","contents":{"compression":"gzip","source":"data:;base64,`+compressedKubeconfig+`"}}]}}`)
+
+	output := filepath.Join(t.TempDir(), "gather.tar.gz")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := Gather(ctx, GatherConfig{
+		AssetDir:            assetDir,
+		PodManifestPath:     podManifestPath,
+		External:            true,
+		RequiredPodPrefixes: map[string][]string{"kube-system/kube-apiserver": {"kube-system/kube-apiserver"}},
+		ConfigFiles:         []string{ignition, bootstrapIgnition},
+		Output:              output,
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	files := readGatherArchive(t, output)
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	expected := []string{
+		"bootstrap-gather/asset-creation-report.json",
+		"bootstrap-gather/asset-dir.json",
+		"bootstrap-gather/config/bootstrap.ign",
+		"bootstrap-gather/config/cluster-config.yaml",
+		"bootstrap-gather/config/master.ign",
+		"bootstrap-gather/errors.txt",
+		"bootstrap-gather/operator-node-statuses.json",
+		"bootstrap-gather/owned-static-manifests.json",
+	}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected files %v, got %v", expected, names)
+	}
+
+	for name, content := range files {
+		for _, secret := range []string{"secret-service-account-key", "secret-token", "dXNlcjpwYXNz", pullSecret, compressedKubeconfig} {
+			if strings.Contains(content, secret) {
+				t.Errorf("expected %s to be redacted from %s: %s", secret, name, content)
+			}
+		}
+	}
+	if !strings.Contains(files["bootstrap-gather/errors.txt"], "pods.json: ") {
+		t.Errorf("expected the unreachable apiserver to be recorded, got %q", files["bootstrap-gather/errors.txt"])
+	}
+
+	if !strings.Contains(files["bootstrap-gather/config/bootstrap.ign"], "/opt/openshift/auth/kubeconfig") {
+		t.Errorf("expected the Ignition config to be kept apart from its secrets, got %s", files["bootstrap-gather/config/bootstrap.ign"])
+	}
+
+	var owned []ownedManifest
+	if err := json.Unmarshal([]byte(files["bootstrap-gather/owned-static-manifests.json"]), &owned); err != nil {
+		t.Fatal(err)
+	}
+	states := map[string]string{}
+	for _, m := range owned {
+		states[m.Manifest] = m.State
+	}
+	if expected := map[string]string{"apiserver.yaml": "Present", "etcd.yaml": "Modified", "scheduler.yaml": "Missing"}; !reflect.DeepEqual(states, expected) {
+		t.Errorf("expected owned manifests %v, got %v", expected, states)
+	}
+}
+
+func TestGatherConfigFileNamesAndClientErrors(t *testing.T) {
+	assetDir, configDir := t.TempDir(), t.TempDir()
+	// the kubeconfig loads, but no client can be built from it
+	writeTestFile(t, filepath.Join(assetDir, assetPathAdminKubeConfig), `apiVersion: v1
+kind: Config
+clusters:
+- name: cluster
+  cluster:
+    server: https://127.0.0.1:1
+    certificate-authority-data: `+base64.StdEncoding.EncodeToString([]byte("not a certificate"))+`
+contexts:
+- name: admin
+  context:
+    cluster: cluster
+    user: admin
+current-context: admin
+users:
+- name: admin
+  user: {}
+`)
+	writeTestFile(t, filepath.Join(configDir, "a", "master.ign"), "a")
+	writeTestFile(t, filepath.Join(configDir, "b", "master.ign"), "b")
+	writeTestFile(t, filepath.Join(assetDir, assetPathClusterConfig), "cluster config")
+	writeTestFile(t, filepath.Join(configDir, "cluster-config.yaml"), "other cluster config")
+
+	output := filepath.Join(t.TempDir(), "gather.tar.gz")
+	if err := Gather(context.Background(), GatherConfig{
+		AssetDir:        assetDir,
+		PodManifestPath: t.TempDir(),
+		External:        true,
+		ConfigFiles: []string{
+			filepath.Join(configDir, "a", "master.ign"),
+			filepath.Join(configDir, "b", "master.ign"),
+			filepath.Join(configDir, "cluster-config.yaml"),
+		},
+		Output: output,
+	}); err != nil {
+		t.Fatalf("expected the archive to be written, got %v", err)
+	}
+
+	files := readGatherArchive(t, output)
+	for name, content := range map[string]string{
+		"bootstrap-gather/config/master.ign":            "a",
+		"bootstrap-gather/config/master-1.ign":          "b",
+		"bootstrap-gather/config/cluster-config.yaml":   "cluster config",
+		"bootstrap-gather/config/cluster-config-1.yaml": "other cluster config",
+	} {
+		if files[name] != content {
+			t.Errorf("expected %s to be %q, got %q", name, content, files[name])
+		}
+	}
+	if !strings.Contains(files["bootstrap-gather/errors.txt"], "client: ") {
+		t.Errorf("expected the client error to be recorded, got %q", files["bootstrap-gather/errors.txt"])
+	}
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readGatherArchive(t *testing.T, path string) map[string]string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return files
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[hdr.Name] = string(data)
+	}
+}
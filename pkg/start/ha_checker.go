@@ -3,9 +3,11 @@ package start
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
+	operatorv1 "github.com/openshift/api/operator/v1"
 	operatorversionedclient "github.com/openshift/client-go/operator/clientset/versioned"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -13,20 +15,31 @@ import (
 	"k8s.io/apimachinery/pkg/util/wait"
 )
 
-// waitForSelfHostedControlPlaneAvailabilityBeforeTearDown will wait until the
-// following conditions are true:
+// NewControlPlaneAvailabilityGate returns the gate which waits until the following conditions
+// are true:
 // a) at least two master nodes have API available
 // b) at least two master node has scheduler installed
 // c) at least two master node has kcm installed
-func waitForSelfHostedControlPlaneAvailabilityBeforeTearDown(loopbackOperatorClient operatorversionedclient.Interface, timeout time.Duration) error {
-	return waitFor([]*poller{
+// d) etcd has quorum on the master nodes, without counting the bootstrap member
+func NewControlPlaneAvailabilityGate(loopbackOperatorClient operatorversionedclient.Interface, controlPlaneReplicas int, timeout time.Duration) AvailabilityGate {
+	return &pollerGate{
+		name:    "self hosted control plane to be available",
+		pollers: controlPlaneAvailabilityPollers(loopbackOperatorClient, controlPlaneReplicas, timeout),
+	}
+}
+
+func controlPlaneAvailabilityPollers(loopbackOperatorClient operatorversionedclient.Interface, controlPlaneReplicas int, timeout time.Duration) []*poller {
+	return []*poller{
 		newAPIAvailabilityPoller(loopbackOperatorClient, timeout),
 		newSchedulerAvailabilityPoller(loopbackOperatorClient, timeout),
 		newKCMAvailabilityPoller(loopbackOperatorClient, timeout),
-	})
+		newEtcdQuorumPoller(loopbackOperatorClient, controlPlaneReplicas, timeout),
+	}
 }
 
-func waitFor(pollers []*poller) error {
+// waitFor waits until the conditions of all pollers are satisfied, each up to its timeout, or
+// until ctx is done.
+func waitFor(ctx context.Context, pollers []*poller) error {
 	wg := sync.WaitGroup{}
 	wg.Add(len(pollers))
 
@@ -35,7 +48,7 @@ func waitFor(pollers []*poller) error {
 		p := pollers[i]
 		go func(p *poller) {
 			defer wg.Done()
-			if err := p.poll(); err != nil {
+			if err := p.poll(ctx); err != nil {
 				errCh <- err
 			}
 		}(p)
@@ -51,6 +64,20 @@ func waitFor(pollers []*poller) error {
 	return utilerrors.NewAggregate(errs)
 }
 
+// pollerGate is an AvailabilityGate waiting for the conditions of its pollers.
+type pollerGate struct {
+	name    string
+	pollers []*poller
+}
+
+func (g *pollerGate) Name() string {
+	return g.name
+}
+
+func (g *pollerGate) Wait(ctx context.Context) error {
+	return waitFor(ctx, g.pollers)
+}
+
 type poller struct {
 	// description of the condition
 	what    string
@@ -61,9 +88,9 @@ type poller struct {
 	condition func(context.Context) (reason string, satisfied bool)
 }
 
-func (p poller) poll() error {
+func (p poller) poll(ctx context.Context) error {
 	UserOutput("Waiting up to %s for condition: %s\n", p.timeout, p.what)
-	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
 	defer cancel()
 
 	lastMsg := ""
@@ -178,3 +205,66 @@ func newKCMAvailabilityPoller(loopbackOperatorClient operatorversionedclient.Int
 		},
 	}
 }
+
+func newEtcdQuorumPoller(loopbackOperatorClient operatorversionedclient.Interface, controlPlaneReplicas int, timeout time.Duration) *poller {
+	return &poller{
+		timeout: timeout,
+		what:    fmt.Sprintf("etcd should have quorum (%d of %d members) on the master nodes without the bootstrap member", etcdQuorum(controlPlaneReplicas), controlPlaneReplicas),
+		condition: func(ctx context.Context) (string, bool) {
+			client := loopbackOperatorClient.OperatorV1().Etcds()
+			etcd, err := client.Get(ctx, "cluster", metav1.GetOptions{})
+			if err != nil {
+				return fmt.Sprintf("error getting etcds/cluster - %v", err), false
+			}
+			return etcdQuorumStatus(etcd, controlPlaneReplicas)
+		},
+	}
+}
+
+// etcdQuorum returns the number of members needed for quorum in an etcd cluster of the given size.
+func etcdQuorum(members int) int {
+	return members/2 + 1
+}
+
+// etcdQuorumStatus checks whether enough master members of etcds/cluster are at a
+// revision to hold quorum on their own. The bootstrap member does not run on a node,
+// hence it never shows up in the NodeStatuses and is not counted.
+func etcdQuorumStatus(etcd *operatorv1.Etcd, controlPlaneReplicas int) (string, bool) {
+	statuses := etcd.Status.NodeStatuses
+	if len(statuses) == 0 {
+		return "NodeStatuses for etcds/cluster is empty", false
+	}
+
+	available := 0
+	msg := ""
+	missing := []string{}
+	for _, status := range statuses {
+		msg = fmt.Sprintf("%s [%s at Current: %d, Target: %d]", msg, status.NodeName, status.CurrentRevision, status.TargetRevision)
+		if status.CurrentRevision >= 1 {
+			available++
+		} else {
+			missing = append(missing, status.NodeName)
+		}
+	}
+	for i := len(statuses); i < controlPlaneReplicas; i++ {
+		missing = append(missing, fmt.Sprintf("<unknown master %d>", i+1))
+	}
+	if len(missing) > 0 {
+		msg = fmt.Sprintf("%s, missing members: %s", msg, strings.Join(missing, ", "))
+	}
+
+	// the operator conditions also account for the bootstrap member, so they only
+	// veto, they cannot satisfy the quorum on their own.
+	membersAvailable := true
+	for _, cond := range etcd.Status.Conditions {
+		if cond.Type == "EtcdMembersAvailable" && cond.Status != operatorv1.ConditionTrue {
+			membersAvailable = false
+			msg = fmt.Sprintf("%s, %s=%s: %s", msg, cond.Type, cond.Status, cond.Message)
+		}
+		if cond.Type == "EtcdMembersDegraded" && cond.Status == operatorv1.ConditionTrue {
+			msg = fmt.Sprintf("%s, %s=%s: %s", msg, cond.Type, cond.Status, cond.Message)
+		}
+	}
+
+	return fmt.Sprintf("etcds/cluster NodeStatuses: %s", msg), membersAvailable && available >= etcdQuorum(controlPlaneReplicas)
+}
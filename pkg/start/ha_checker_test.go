@@ -2,9 +2,13 @@ package start
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"testing"
 	"time"
 
+	operatorv1 "github.com/openshift/api/operator/v1"
+
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 )
 
@@ -68,7 +72,7 @@ func TestWaitForAvailabilityBeforeTearDown(t *testing.T) {
 		t.Run(test.name, func(t *testing.T) {
 			t.Parallel()
 
-			err := waitFor(test.pollers)
+			err := waitFor(context.Background(), test.pollers)
 			switch {
 			case test.errCount > 0:
 				if err == nil {
@@ -89,3 +93,83 @@ func TestWaitForAvailabilityBeforeTearDown(t *testing.T) {
 		})
 	}
 }
+
+func TestEtcdQuorumStatus(t *testing.T) {
+	nodeStatuses := func(revisions ...int32) []operatorv1.NodeStatus {
+		statuses := []operatorv1.NodeStatus{}
+		for i, r := range revisions {
+			statuses = append(statuses, operatorv1.NodeStatus{NodeName: fmt.Sprintf("master-%d", i), CurrentRevision: r})
+		}
+		return statuses
+	}
+	tests := []struct {
+		name        string
+		statuses    []operatorv1.NodeStatus
+		conditions  []operatorv1.OperatorCondition
+		satisfied   bool
+		wantMissing []string
+	}{
+		{
+			name:      "no node statuses",
+			satisfied: false,
+		},
+		{
+			name:        "one of three members",
+			statuses:    nodeStatuses(1, 0, 0),
+			satisfied:   false,
+			wantMissing: []string{"master-1", "master-2"},
+		},
+		{
+			name:        "two of three members reported",
+			statuses:    nodeStatuses(1, 1),
+			satisfied:   true,
+			wantMissing: []string{"<unknown master 3>"},
+		},
+		{
+			name:      "all members",
+			statuses:  nodeStatuses(3, 2, 1),
+			satisfied: true,
+		},
+		{
+			name:     "members not available",
+			statuses: nodeStatuses(1, 1, 1),
+			conditions: []operatorv1.OperatorCondition{
+				{Type: "EtcdMembersAvailable", Status: operatorv1.ConditionFalse, Message: "1 of 4 members are available"},
+			},
+			satisfied: false,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			etcd := &operatorv1.Etcd{}
+			etcd.Status.NodeStatuses = test.statuses
+			etcd.Status.Conditions = test.conditions
+
+			reason, satisfied := etcdQuorumStatus(etcd, 3)
+			if satisfied != test.satisfied {
+				t.Errorf("expected satisfied=%v, got %v: %s", test.satisfied, satisfied, reason)
+			}
+			for _, m := range test.wantMissing {
+				if !strings.Contains(reason, m) {
+					t.Errorf("expected %q to be reported missing, got: %s", m, reason)
+				}
+			}
+		})
+	}
+}
+
+func TestIsTimeout(t *testing.T) {
+	timedOut := waitFor(context.Background(), []*poller{
+		{
+			timeout:   time.Second,
+			what:      "foo",
+			condition: func(context.Context) (string, bool) { return "not satisfied yet", false },
+		},
+	})
+	if !IsTimeout(timedOut) {
+		t.Errorf("expected the error of a timed out poller to be a timeout, got %v", timedOut)
+	}
+	if IsTimeout(fmt.Errorf("error getting kubeconfig")) {
+		t.Errorf("expected other errors not to be timeouts")
+	}
+}
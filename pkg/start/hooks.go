@@ -0,0 +1,156 @@
+package start
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// HookPhase is a point of the bootstrap sequence at which hooks are run.
+type HookPhase string
+
+const (
+	// HookBeforeControlPlaneStart is before the bootstrap control plane is launched.
+	HookBeforeControlPlaneStart HookPhase = "before-control-plane-start"
+	// HookAfterAPIUp is after the API of the bootstrap control plane is up.
+	HookAfterAPIUp HookPhase = "after-api-up"
+	// HookAfterPodsRunning is after the required pods are running and ready.
+	HookAfterPodsRunning HookPhase = "after-pods-running"
+	// HookBeforeBootstrapSuccess is after the availability gates are satisfied and before the
+	// bootstrap-success event is sent.
+	HookBeforeBootstrapSuccess HookPhase = "before-bootstrap-success"
+	// HookBeforeTeardown is before the bootstrap control plane is torn down.
+	HookBeforeTeardown HookPhase = "before-teardown"
+	// HookAfterTeardown is after the bootstrap control plane is torn down.
+	HookAfterTeardown HookPhase = "after-teardown"
+)
+
+// HookPhases are all hook phases in the order of the bootstrap sequence.
+var HookPhases = []HookPhase{
+	HookBeforeControlPlaneStart,
+	HookAfterAPIUp,
+	HookAfterPodsRunning,
+	HookBeforeBootstrapSuccess,
+	HookBeforeTeardown,
+	HookAfterTeardown,
+}
+
+// HookFailurePolicy decides what happens if a hook fails or times out.
+type HookFailurePolicy string
+
+const (
+	// HookFailurePolicyFail fails the bootstrap.
+	HookFailurePolicyFail HookFailurePolicy = "Fail"
+	// HookFailurePolicyIgnore reports the failure and continues with the next hook.
+	HookFailurePolicyIgnore HookFailurePolicy = "Ignore"
+)
+
+// Environment variables passed to hooks, in addition to those of cluster-bootstrap.
+const (
+	hookEnvPhase      = "BOOTSTRAP_HOOK_PHASE"
+	hookEnvAssetDir   = "BOOTSTRAP_ASSET_DIR"
+	hookEnvKubeconfig = "KUBECONFIG"
+)
+
+// PhaseHooks runs the hooks of the phases of the bootstrap sequence.
+type PhaseHooks interface {
+	RunHooks(ctx context.Context, phase HookPhase) error
+}
+
+// HooksConfig configures NewHooksDir.
+type HooksConfig struct {
+	// Dir has a subdirectory per phase, named like the phase, with the executables to run in
+	// lexical order. Other files are skipped.
+	Dir string
+	// Timeout is how long a single hook may run. Zero means no timeout.
+	Timeout       time.Duration
+	FailurePolicy HookFailurePolicy
+	// AssetDir and Kubeconfig are passed to the hooks.
+	AssetDir   string
+	Kubeconfig string
+}
+
+// NewHooksDir returns the PhaseHooks of the start command, which runs the executables of the
+// phase subdirectory of the hooks dir.
+func NewHooksDir(config HooksConfig) PhaseHooks {
+	return &hooksDir{config: config}
+}
+
+type hooksDir struct {
+	config HooksConfig
+}
+
+func (h *hooksDir) RunHooks(ctx context.Context, phase HookPhase) error {
+	hooks, err := h.hooks(phase)
+	if err != nil {
+		return h.failed(phase, err)
+	}
+	for _, hook := range hooks {
+		UserOutput("Running %s hook %s\n", phase, hook)
+		if err := h.run(ctx, phase, hook); err != nil {
+			if err := h.failed(phase, fmt.Errorf("hook %s failed: %w", hook, err)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// hooks returns the executables of the phase subdirectory sorted by name.
+func (h *hooksDir) hooks(phase HookPhase) ([]string, error) {
+	dir := filepath.Join(h.config.Dir, string(phase))
+	infos, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var hooks []string
+	for _, info := range infos {
+		path := filepath.Join(dir, info.Name())
+		if info.IsDir() || info.Mode()&0111 == 0 {
+			UserOutput("Skipping %s hook %s, it is not an executable file\n", phase, path)
+			continue
+		}
+		hooks = append(hooks, path)
+	}
+	sort.Strings(hooks)
+	return hooks, nil
+}
+
+// run runs the hook with the phase, asset dir and kubeconfig in its environment.
+func (h *hooksDir) run(ctx context.Context, phase HookPhase, hook string) error {
+	if h.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.config.Timeout)
+		defer cancel()
+	}
+	cmd := exec.CommandContext(ctx, hook)
+	cmd.Env = append(os.Environ(),
+		hookEnvPhase+"="+string(phase),
+		hookEnvAssetDir+"="+h.config.AssetDir,
+		hookEnvKubeconfig+"="+h.config.Kubeconfig,
+	)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("timed out after %v", h.config.Timeout)
+	}
+	return err
+}
+
+// failed returns the error of a failed hook, unless the failure policy ignores it.
+func (h *hooksDir) failed(phase HookPhase, err error) error {
+	if h.config.FailurePolicy == HookFailurePolicyIgnore {
+		UserOutput("Ignoring failure of %s hooks: %v\n", phase, err)
+		return nil
+	}
+	return fmt.Errorf("%s hooks: %w", phase, err)
+}
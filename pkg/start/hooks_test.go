@@ -0,0 +1,108 @@
+package start
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHooksDir(t *testing.T) {
+	tests := []struct {
+		name          string
+		hooks         map[string]string
+		failurePolicy HookFailurePolicy
+		timeout       time.Duration
+		expectedOut   string
+		expectedErr   string
+	}{
+		{
+			name:        "no hooks",
+			expectedOut: "",
+		},
+		{
+			name: "hooks run in lexical order with environment",
+			hooks: map[string]string{
+				"20-second": `echo "second $BOOTSTRAP_HOOK_PHASE $BOOTSTRAP_ASSET_DIR $KUBECONFIG" >> "$HOOK_OUT"`,
+				"10-first":  `echo "first $BOOTSTRAP_HOOK_PHASE" >> "$HOOK_OUT"`,
+				"README":    "",
+			},
+			expectedOut: "first before-teardown\nsecond before-teardown /assets /assets/auth/kubeconfig-loopback\n",
+		},
+		{
+			name: "failing hook fails",
+			hooks: map[string]string{
+				"10-fail": "exit 3",
+				"20-next": `echo next >> "$HOOK_OUT"`,
+			},
+			failurePolicy: HookFailurePolicyFail,
+			expectedErr:   "before-teardown hooks: hook",
+		},
+		{
+			name: "failing hook is ignored",
+			hooks: map[string]string{
+				"10-fail": "exit 3",
+				"20-next": `echo next >> "$HOOK_OUT"`,
+			},
+			failurePolicy: HookFailurePolicyIgnore,
+			expectedOut:   "next\n",
+		},
+		{
+			name: "hook times out",
+			hooks: map[string]string{
+				"10-sleep": "exec sleep 10",
+			},
+			failurePolicy: HookFailurePolicyFail,
+			timeout:       100 * time.Millisecond,
+			expectedErr:   "timed out after 100ms",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			out := filepath.Join(t.TempDir(), "out")
+			t.Setenv("HOOK_OUT", out)
+			if len(tt.hooks) > 0 {
+				phaseDir := filepath.Join(dir, string(HookBeforeTeardown))
+				if err := os.Mkdir(phaseDir, 0755); err != nil {
+					t.Fatal(err)
+				}
+				for name, script := range tt.hooks {
+					mode := os.FileMode(0755)
+					if len(script) == 0 {
+						mode = 0644
+					}
+					if err := os.WriteFile(filepath.Join(phaseDir, name), []byte("#!/bin/sh\n"+script+"\n"), mode); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+
+			hooks := NewHooksDir(HooksConfig{
+				Dir:           dir,
+				Timeout:       tt.timeout,
+				FailurePolicy: tt.failurePolicy,
+				AssetDir:      "/assets",
+				Kubeconfig:    "/assets/auth/kubeconfig-loopback",
+			})
+			err := hooks.RunHooks(context.Background(), HookBeforeTeardown)
+			if len(tt.expectedErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.expectedErr, err)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			data, err := os.ReadFile(out)
+			if err != nil && !os.IsNotExist(err) {
+				t.Fatal(err)
+			}
+			if string(data) != tt.expectedOut {
+				t.Errorf("expected hook output %q, got %q", tt.expectedOut, string(data))
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package start
+
+import (
+	"bufio"
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// verifiedAssetDirs are the asset directories whose files must all be listed in the checksum manifest.
+var verifiedAssetDirs = []string{
+	assetPathSecrets,
+	filepath.Dir(assetPathAdminKubeConfig),
+	assetPathManifests,
+	assetPathBootstrapManifests,
+}
+
+// verifyAssetDir checks the files of the verified asset directories against the checksum manifest,
+// which is of the sha256sum format with paths relative to the asset directory. If a public key is
+// given, the manifest must be signed with the detached signature.
+func verifyAssetDir(assetDir, checksumsFile, signatureFile, publicKeyFile string) error {
+	data, err := ioutil.ReadFile(checksumsFile)
+	if err != nil {
+		return fmt.Errorf("failed to read asset checksums: %w", err)
+	}
+	if len(publicKeyFile) > 0 {
+		if err := verifySignature(data, signatureFile, publicKeyFile); err != nil {
+			return fmt.Errorf("failed to verify signature of asset checksums %s: %w", checksumsFile, err)
+		}
+		UserOutput("Verified signature of asset checksums %s\n", checksumsFile)
+	}
+
+	checksums, err := parseChecksums(data)
+	if err != nil {
+		return fmt.Errorf("failed to parse asset checksums %s: %w", checksumsFile, err)
+	}
+
+	var problems []string
+	found := map[string]bool{}
+	for _, dir := range verifiedAssetDirs {
+		err := filepath.Walk(filepath.Join(assetDir, dir), func(path string, info os.FileInfo, err error) error {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				return nil
+			}
+			rel, err := filepath.Rel(assetDir, path)
+			if err != nil {
+				return err
+			}
+			rel = filepath.ToSlash(rel)
+			found[rel] = true
+			if !info.Mode().IsRegular() {
+				problems = append(problems, fmt.Sprintf("%s: not a regular file", rel))
+			} else if _, ok := checksums[rel]; !ok {
+				problems = append(problems, fmt.Sprintf("%s: extra file", rel))
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to walk asset directory: %w", err)
+		}
+	}
+
+	for rel, want := range checksums {
+		path := filepath.Join(assetDir, filepath.FromSlash(rel))
+		info, err := os.Lstat(path)
+		switch {
+		case os.IsNotExist(err):
+			problems = append(problems, fmt.Sprintf("%s: missing file", rel))
+			continue
+		case err != nil:
+			return err
+		case !info.Mode().IsRegular():
+			if !found[rel] {
+				problems = append(problems, fmt.Sprintf("%s: not a regular file", rel))
+			}
+			continue
+		}
+		got, err := sha256File(path)
+		if err != nil {
+			return err
+		}
+		if got != want {
+			problems = append(problems, fmt.Sprintf("%s: modified file, sha256 is %s, expected %s", rel, got, want))
+		}
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("asset directory %s does not match the asset checksums %s:\n%s", assetDir, checksumsFile, strings.Join(problems, "\n"))
+	}
+
+	UserOutput("Verified %d files of asset directory %s\n", len(checksums), assetDir)
+	return nil
+}
+
+// parseChecksums parses lines of <sha256>  <path>, as written by sha256sum, into a map from
+// slash-separated path to checksum. Paths must be relative and must not leave the asset directory.
+func parseChecksums(data []byte) (map[string]string, error) {
+	checksums := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if len(text) == 0 {
+			continue
+		}
+		ss := strings.SplitN(text, " ", 2)
+		if len(ss) != 2 {
+			return nil, fmt.Errorf("line %d: expected <sha256>  <path>", line)
+		}
+		sum := strings.ToLower(ss[0])
+		if b, err := hex.DecodeString(sum); err != nil || len(b) != sha256.Size {
+			return nil, fmt.Errorf("line %d: invalid sha256 checksum %q", line, ss[0])
+		}
+		// sha256sum marks binary mode with a "*" in front of the path
+		rel := strings.TrimPrefix(strings.TrimLeft(ss[1], " "), "*")
+		clean := filepath.ToSlash(filepath.Clean(filepath.FromSlash(rel)))
+		if filepath.IsAbs(rel) || clean == ".." || strings.HasPrefix(clean, "../") {
+			return nil, fmt.Errorf("line %d: path %q is not within the asset directory", line, rel)
+		}
+		if _, ok := checksums[clean]; ok {
+			return nil, fmt.Errorf("line %d: duplicate path %q", line, rel)
+		}
+		checksums[clean] = sum
+	}
+	return checksums, scanner.Err()
+}
+
+func sha256File(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// verifySignature verifies the detached signature, raw or base64 encoded, of data with the PEM
+// encoded public key. RSA (PKCS #1 v1.5) and ECDSA signatures are expected over the SHA-256
+// digest, Ed25519 signatures over the data itself.
+func verifySignature(data []byte, signatureFile, publicKeyFile string) error {
+	if len(signatureFile) == 0 {
+		return fmt.Errorf("a signature is required with a public key")
+	}
+	signature, err := ioutil.ReadFile(signatureFile)
+	if err != nil {
+		return err
+	}
+	if decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature))); err == nil {
+		signature = decoded
+	}
+
+	keyData, err := ioutil.ReadFile(publicKeyFile)
+	if err != nil {
+		return err
+	}
+	block, _ := pem.Decode(keyData)
+	if block == nil {
+		return fmt.Errorf("no PEM block found in public key %s", publicKeyFile)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("failed to parse public key %s: %w", publicKeyFile, err)
+	}
+
+	digest := sha256.Sum256(data)
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature)
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(key, digest[:], signature) {
+			return fmt.Errorf("ecdsa: verification error")
+		}
+		return nil
+	case ed25519.PublicKey:
+		if !ed25519.Verify(key, data, signature) {
+			return fmt.Errorf("ed25519: verification error")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported public key type %T", key)
+	}
+}
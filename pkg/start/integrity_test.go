@@ -0,0 +1,174 @@
+package start
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeChecksums writes a checksum manifest of all files in the verified asset directories.
+func writeChecksums(t *testing.T, assetDir string) string {
+	var lines []string
+	for _, dir := range verifiedAssetDirs {
+		filepath.Walk(filepath.Join(assetDir, dir), func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return nil
+			}
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rel, _ := filepath.Rel(assetDir, path)
+			sum := sha256.Sum256(data)
+			lines = append(lines, fmt.Sprintf("%s  %s", hex.EncodeToString(sum[:]), rel))
+			return nil
+		})
+	}
+	checksums := filepath.Join(t.TempDir(), "sha256sum.txt")
+	if err := ioutil.WriteFile(checksums, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return checksums
+}
+
+func TestVerifyAssetDir(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(assetDir string) error
+		problems []string
+	}{
+		{
+			name:   "unmodified",
+			modify: func(string) error { return nil },
+		},
+		{
+			name: "modified, missing and extra files",
+			modify: func(assetDir string) error {
+				if err := ioutil.WriteFile(filepath.Join(assetDir, assetPathSecrets, secrets[0]), []byte("modified"), 0644); err != nil {
+					return err
+				}
+				if err := os.Remove(filepath.Join(assetDir, assetPathBootstrapManifests, manifests[0])); err != nil {
+					return err
+				}
+				return ioutil.WriteFile(filepath.Join(assetDir, assetPathSecrets, "extra.key"), []byte("extra"), 0644)
+			},
+			problems: []string{
+				"tls/secret-1.yaml: modified file",
+				"bootstrap-manifests/pod-1.yaml: missing file",
+				"tls/extra.key: extra file",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assetDir, podManifestPath := setUp(t)
+			defer tearDown(assetDir, podManifestPath, t)
+
+			checksums := writeChecksums(t, assetDir)
+			if err := test.modify(assetDir); err != nil {
+				t.Fatal(err)
+			}
+
+			err := verifyAssetDir(assetDir, checksums, "", "")
+			if len(test.problems) == 0 {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected error")
+			}
+			for _, p := range test.problems {
+				if !strings.Contains(err.Error(), p) {
+					t.Errorf("expected %q to be reported, got: %v", p, err)
+				}
+			}
+		})
+	}
+}
+
+func TestVerifyAssetDirSignature(t *testing.T) {
+	assetDir, podManifestPath := setUp(t)
+	defer tearDown(assetDir, podManifestPath, t)
+	checksums := writeChecksums(t, assetDir)
+	data, err := ioutil.ReadFile(checksums)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey := filepath.Join(t.TempDir(), "key.pub")
+	if err := ioutil.WriteFile(publicKey, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	signature := checksums + ".sig"
+	if err := ioutil.WriteFile(signature, ed25519.Sign(priv, data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyAssetDir(assetDir, checksums, signature, publicKey); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if err := ioutil.WriteFile(checksums, append(data, []byte("# tampered\n")...), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyAssetDir(assetDir, checksums, signature, publicKey); err == nil {
+		t.Errorf("expected signature verification to fail")
+	}
+}
+
+func TestParseChecksums(t *testing.T) {
+	sum := strings.Repeat("ab", sha256.Size)
+	tests := []struct {
+		name    string
+		data    string
+		want    map[string]string
+		wantErr bool
+	}{
+		{"text and binary mode", sum + "  tls/a.key\n" + sum + " *auth/kubeconfig\n\n", map[string]string{"tls/a.key": sum, "auth/kubeconfig": sum}, false},
+		{"invalid checksum", "abc  tls/a.key\n", nil, true},
+		{"absolute path", sum + "  /etc/passwd\n", nil, true},
+		{"parent path", sum + "  tls/../../etc/passwd\n", nil, true},
+		{"duplicate path", sum + "  tls/a.key\n" + sum + "  ./tls/a.key\n", nil, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := parseChecksums([]byte(test.data))
+			if (err != nil) != test.wantErr {
+				t.Fatalf("parseChecksums() error = %v, wantErr %v", err, test.wantErr)
+			}
+			if !test.wantErr && fmt.Sprint(got) != fmt.Sprint(test.want) {
+				t.Errorf("parseChecksums() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestAssetChecksumsRejectManifestOverrideDirs(t *testing.T) {
+	_, err := NewStartCommand(Config{
+		AssetDir:             "/assets",
+		AssetChecksums:       "/assets.sha256",
+		ManifestOverrideDirs: []string{"/overrides"},
+	})
+	if err == nil {
+		t.Fatalf("expected manifest override directories to be rejected with asset checksums")
+	}
+}
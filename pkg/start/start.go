@@ -2,17 +2,17 @@ package start
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
 	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
-	"sync"
 	"time"
 
+	configversionedclient "github.com/openshift/client-go/config/clientset/versioned"
 	operatorversionedclient "github.com/openshift/client-go/operator/clientset/versioned"
-	"github.com/openshift/library-go/pkg/assets/create"
 
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -21,6 +21,10 @@ import (
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/utils/clock"
+
+	"github.com/openshift/cluster-bootstrap/pkg/create"
+	"github.com/openshift/cluster-bootstrap/pkg/installconfig"
 )
 
 const (
@@ -30,55 +34,129 @@ const (
 
 	// how long we wait for self hosted control plane to be available.
 	// API, scheduler, and kcm each should be available on at least
-	// two master nodes.
+	// two master nodes, and etcd should have quorum on the master nodes.
 	controlPlaneAvailabaleWaitTimeout = 30 * time.Minute
 
+	// how long we wait for the required cluster operators to report
+	// the required conditions.
+	clusterOperatorsAvailableWaitTimeout = 30 * time.Minute
+
 	// This is the minimum amount of time cluster bootstrap will wait
 	// after it determines that self hosted control plane is available
 	// so that load balancers have some buffer time to discover
 	// the apiservers on the master nodes
 	minimumTeardownDelay = 30 * time.Second
+
+	// how often the number of created manifests is printed while assets are created
+	assetProgressInterval = 30 * time.Second
+
+	// the events telling the installer that the bootstrap control plane can be torn down,
+	// and that all assets are created.
+	bootstrapEventNamespace = "kube-system"
+	bootstrapSuccessEvent   = "bootstrap-success"
+	bootstrapFinishedEvent  = "bootstrap-finished"
 )
 
 type Config struct {
-	AssetDir             string
-	PodManifestPath      string
-	Strict               bool
-	RequiredPodPrefixes  map[string][]string
-	WaitForTearDownEvent string
-	EarlyTearDown        bool
-	TerminationTimeout   time.Duration
-	TearDownDelay        time.Duration
-	AssetsCreatedTimeout time.Duration
+	AssetDir                 string
+	AssetArchive             string
+	PodManifestPath          string
+	Strict                   bool
+	RequiredPodPrefixes      map[string][]string
+	WaitForTearDownEvent     string
+	EarlyTearDown            bool
+	TerminationTimeout       time.Duration
+	TearDownDelay            time.Duration
+	AssetsCreatedTimeout     time.Duration
+	RequiredClusterOperators []ClusterOperatorRequirement
+	ManifestSelection        create.Selection
+	ManifestOverrideDirs     []string
+	AssetChecksums           string
+	AssetChecksumsSignature  string
+	AssetChecksumsPublicKey  string
+	HooksDir                 string
+	HookTimeout              time.Duration
+	HookFailurePolicy        HookFailurePolicy
 }
 
 type startCommand struct {
-	podManifestPath      string
-	assetDir             string
-	strict               bool
-	requiredPodPrefixes  map[string][]string
-	waitForTearDownEvent string
-	earlyTearDown        bool
-	terminationTimeout   time.Duration
-	tearDownDelay        time.Duration
-	assetsCreatedTimeout time.Duration
+	podManifestPath          string
+	assetDir                 string
+	assetArchive             string
+	strict                   bool
+	requiredPodPrefixes      map[string][]string
+	waitForTearDownEvent     string
+	earlyTearDown            bool
+	terminationTimeout       time.Duration
+	tearDownDelay            time.Duration
+	assetsCreatedTimeout     time.Duration
+	requiredClusterOperators []ClusterOperatorRequirement
+	manifestSelection        create.Selection
+	manifestOverrideDirs     []string
+	assetChecksums           string
+	assetChecksumsSignature  string
+	assetChecksumsPublicKey  string
+	hooksDir                 string
+	hookTimeout              time.Duration
+	hookFailurePolicy        HookFailurePolicy
 }
 
 func NewStartCommand(config Config) (*startCommand, error) {
+	// the asset checksums only cover the asset directory, manifests of override directories
+	// would win over verified manifests
+	if len(config.AssetChecksums) > 0 && len(config.ManifestOverrideDirs) > 0 {
+		return nil, errors.New("manifest override directories cannot be used with asset checksums")
+	}
 	return &startCommand{
-		assetDir:             config.AssetDir,
-		podManifestPath:      config.PodManifestPath,
-		strict:               config.Strict,
-		requiredPodPrefixes:  config.RequiredPodPrefixes,
-		waitForTearDownEvent: config.WaitForTearDownEvent,
-		earlyTearDown:        config.EarlyTearDown,
-		terminationTimeout:   config.TerminationTimeout,
-		tearDownDelay:        config.TearDownDelay,
-		assetsCreatedTimeout: config.AssetsCreatedTimeout,
+		assetDir:                 config.AssetDir,
+		assetArchive:             config.AssetArchive,
+		podManifestPath:          config.PodManifestPath,
+		strict:                   config.Strict,
+		requiredPodPrefixes:      config.RequiredPodPrefixes,
+		waitForTearDownEvent:     config.WaitForTearDownEvent,
+		earlyTearDown:            config.EarlyTearDown,
+		terminationTimeout:       config.TerminationTimeout,
+		tearDownDelay:            config.TearDownDelay,
+		assetsCreatedTimeout:     config.AssetsCreatedTimeout,
+		requiredClusterOperators: config.RequiredClusterOperators,
+		manifestSelection:        config.ManifestSelection,
+		manifestOverrideDirs:     config.ManifestOverrideDirs,
+		assetChecksums:           config.AssetChecksums,
+		assetChecksumsSignature:  config.AssetChecksumsSignature,
+		assetChecksumsPublicKey:  config.AssetChecksumsPublicKey,
+		hooksDir:                 config.HooksDir,
+		hookTimeout:              config.HookTimeout,
+		hookFailurePolicy:        config.HookFailurePolicy,
 	}, nil
 }
 
 func (b *startCommand) Run() error {
+	reportFile := filepath.Join(b.assetDir, assetPathCreationReport)
+	if len(b.assetArchive) > 0 {
+		stagingDir, err := unpackAssetArchive(b.assetArchive)
+		if err != nil {
+			return err
+		}
+		// Remove the staging directory last, after tearing down the bootstrap control plane
+		// and after the remaining assets are created.
+		defer func() {
+			UserOutput("Removing asset staging directory %s\n", stagingDir)
+			if err := removeStagingDir(stagingDir); err != nil {
+				UserOutput("Error removing asset staging directory: %v\n", err)
+			}
+		}()
+		b.assetDir = stagingDir
+		// the staging directory does not survive, keep the report next to the archive
+		reportFile = filepath.Join(filepath.Dir(b.assetArchive), assetPathCreationReport)
+	}
+
+	// Refuse to trust the asset directory if it does not match the checksums.
+	if len(b.assetChecksums) > 0 {
+		if err := verifyAssetDir(b.assetDir, b.assetChecksums, b.assetChecksumsSignature, b.assetChecksumsPublicKey); err != nil {
+			return err
+		}
+	}
+
 	restConfig, err := clientcmd.BuildConfigFromFlags("", filepath.Join(b.assetDir, assetPathAdminKubeConfig))
 	if err != nil {
 		return err
@@ -88,10 +166,23 @@ func (b *startCommand) Run() error {
 		return err
 	}
 
-	isHAControlPlane, err := isHAControlPlane(b.assetDir)
+	controlPlaneReplicas, err := controlPlaneReplicas(b.assetDir)
 	if err != nil {
 		return err
 	}
+	isHAControlPlane := isHAControlPlane(controlPlaneReplicas)
+
+	manifestFilters, err := installConfigManifestFilters(filepath.Join(b.assetDir, assetPathClusterConfig))
+	if err != nil {
+		return err
+	}
+	if !b.manifestSelection.Empty() {
+		UserOutput("Creating %s\n", b.manifestSelection)
+		manifestFilters = append(manifestFilters, b.manifestSelection.ManifestFilter())
+	}
+	if len(b.manifestOverrideDirs) > 0 {
+		UserOutput("Overriding manifests with those of %s, in increasing order of precedence\n", strings.Join(b.manifestOverrideDirs, ", "))
+	}
 
 	// We don't want the client contact the API servers via load-balancer, but only talk to the local API server.
 	// This will speed up the initial "where is working API server" process.
@@ -102,25 +193,9 @@ func (b *startCommand) Run() error {
 	if err != nil {
 		return fmt.Errorf("error creating operator client config: %w", err)
 	}
-
-	bcp := newBootstrapControlPlane(b.assetDir, b.podManifestPath, localClientConfig.Host)
-
-	// Always tear down the bootstrap control plane and clean up manifests and secrets.
-	defer func() {
-		if err := bcp.Teardown(b.terminationTimeout); err != nil {
-			UserOutput("Error tearing down temporary bootstrap control plane: %v\n", err)
-		}
-	}()
-
-	defer func() {
-		// Always report errors.
-		if err != nil {
-			UserOutput("Error: %v\n", err)
-		}
-	}()
-
-	if err = bcp.Start(); err != nil {
-		return err
+	loopbackConfigClient, err := configversionedclient.NewForConfig(localClientConfig)
+	if err != nil {
+		return fmt.Errorf("error creating config client config: %w", err)
 	}
 
 	// Set the ServerName to original hostname so we pass the certificate check.
@@ -133,41 +208,23 @@ func (b *startCommand) Run() error {
 		return err
 	}
 
-	// create assets against localhost apiserver (in the background) and wait for control plane to be up
-	createAssetsInBackground := func(ctx context.Context, cancel func(), client *rest.Config) *sync.WaitGroup {
-		done := sync.WaitGroup{}
-		done.Add(1)
-		go func() {
-			defer done.Done()
-			if err := create.EnsureManifestsCreated(ctx, filepath.Join(b.assetDir, assetPathManifests), client, create.CreateOptions{
-				Verbose: true,
-				StdErr:  os.Stderr,
-			}); err != nil {
-				select {
-				case <-ctx.Done():
-				default:
-					UserOutput("Assert creation failed: %v\n", err)
-					cancel()
-				}
-			}
-		}()
-		return &done
-	}
-	ctx, cancel := context.WithTimeout(context.TODO(), bootstrapPodsRunningTimeout)
-	defer cancel()
-	assetsDone := createAssetsInBackground(ctx, cancel, localClientConfig)
-	if err = waitUntilPodsRunning(ctx, client, b.requiredPodPrefixes); err != nil {
-		return err
-	}
+	// track the outcome of every manifest over all creation runs, and report it when we are done
+	tracker := create.NewTracker()
+	defer func() {
+		UserOutput("Asset creation summary:\n%s", tracker.Summary())
+		if err := tracker.WriteReport(reportFile); err != nil {
+			UserOutput("Error writing asset creation report: %v\n", err)
+		}
+	}()
 
+	var gates []AvailabilityGate
 	if isHAControlPlane {
-		UserOutput("Waiting for self hosted control plane to be available\n")
-		if err = waitForSelfHostedControlPlaneAvailabilityBeforeTearDown(loopbackOperatorClient, controlPlaneAvailabaleWaitTimeout); err != nil {
-			return err
-		}
+		gates = append(gates, NewControlPlaneAvailabilityGate(loopbackOperatorClient, controlPlaneReplicas, controlPlaneAvailabaleWaitTimeout))
+	}
+	if len(b.requiredClusterOperators) > 0 {
+		gates = append(gates, NewClusterOperatorsGate(loopbackConfigClient, b.requiredClusterOperators, clusterOperatorsAvailableWaitTimeout))
 	}
 
-	// if we are here, self hosted control plane is available
 	tearDownDelay := b.tearDownDelay
 	// SNO: no behavior change, if the caller passed tearDownDelay through
 	// command line option, then it takes precedence
@@ -178,84 +235,38 @@ func (b *startCommand) Run() error {
 	if isHAControlPlane && tearDownDelay <= minimumTeardownDelay {
 		tearDownDelay = minimumTeardownDelay
 	}
-	if tearDownDelay > 0 {
-		UserOutput("Waiting %v to give load-balancers time to observe the self-hosted control-plane\n", tearDownDelay)
-		time.Sleep(tearDownDelay)
-	}
-
-	cancel()
-	assetsDone.Wait()
-
-	// notify installer that we are ready to tear down the temporary bootstrap control plane
-	UserOutput("Sending bootstrap-success event.\n")
-	if _, err := client.CoreV1().Events("kube-system").Create(context.Background(), makeBootstrapSuccessEvent("kube-system", "bootstrap-success"), metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
-		return err
-	}
-
-	// continue with assets
-	ctx, cancel = context.WithTimeout(context.Background(), b.assetsCreatedTimeout)
-	defer cancel()
-	if b.earlyTearDown {
-		// switch over to ELB client and continue with the assets
-		assetsDone = createAssetsInBackground(ctx, cancel, restConfig)
-	} else {
-		// we don't tear down the local control plane early. So we can keep using it and enjoy the speed up.
-		assetsDone = createAssetsInBackground(ctx, cancel, localClientConfig)
-	}
-
-	// optionally wait for tear down event coming from the installer. This is necessary to
-	// remove the bootstrap node from the AWS load balancer.
-	if len(b.waitForTearDownEvent) != 0 {
-		ss := strings.Split(b.waitForTearDownEvent, "/")
-		if len(ss) != 2 {
-			return fmt.Errorf("tear down event name of format <namespace>/<event-name> expected, got: %q", b.waitForTearDownEvent)
-		}
-		ns, name := ss[0], ss[1]
-		if err := waitForEvent(context.TODO(), client, ns, name); err != nil {
-			return err
-		}
-		UserOutput("Got %s event.\n", b.waitForTearDownEvent)
-	}
 
-	// tear down the bootstrap control plane early. Set bcp to nil to avoid a second tear down in the defer func.
-	// TODO: tear down early is probably not meaningful, we can tear down
-	// only when the self hosted control plane is available, we should remove
-	// this command line option. Maybe it can only apply to SNO only?
-	// currently it is set to false by bootkube.sh
-	if b.earlyTearDown {
-		err = bcp.Teardown(b.terminationTimeout)
-		bcp = nil
-		if err != nil {
-			UserOutput("Error tearing down temporary bootstrap control plane: %v\n", err)
-		}
+	bootstrapper := &Bootstrapper{
+		ControlPlane: NewStaticPodControlPlane(b.assetDir, b.podManifestPath, localClientConfig.Host),
+		Pods:         NewPodReadinessChecker(client, b.requiredPodPrefixes),
+		Gates:        gates,
+		Assets: NewManifestCreator(filepath.Join(b.assetDir, assetPathManifests), create.CreateOptions{
+			Verbose:         true,
+			StdErr:          os.Stderr,
+			PathFilters:     []create.PathPredicate{b.manifestSelection.PathFilter()},
+			ManifestFilters: manifestFilters,
+			OverrideDirs:    b.manifestOverrideDirs,
+			Tracker:         tracker,
+		}),
+		Events:               NewEventSink(client, clock.RealClock{}),
+		LoopbackRestConfig:   localClientConfig,
+		RestConfig:           restConfig,
+		EarlyTearDown:        b.earlyTearDown,
+		TerminationTimeout:   b.terminationTimeout,
+		TearDownDelay:        tearDownDelay,
+		AssetsCreatedTimeout: b.assetsCreatedTimeout,
+		WaitForTearDownEvent: b.waitForTearDownEvent,
 	}
-
-	// wait for the tail of assets to be created after tear down
-	UserOutput("Waiting for remaining assets to be created.\n")
-	assetsDone.Wait()
-	// We want to fail in case we failed to create some manifests
-	if ctx.Err() == context.DeadlineExceeded {
-		return fmt.Errorf("timed out creating manifests")
+	if len(b.hooksDir) > 0 {
+		bootstrapper.Hooks = NewHooksDir(HooksConfig{
+			Dir:           b.hooksDir,
+			Timeout:       b.hookTimeout,
+			FailurePolicy: b.hookFailurePolicy,
+			AssetDir:      b.assetDir,
+			Kubeconfig:    filepath.Join(b.assetDir, assetPathAdminKubeConfig),
+		})
 	}
-
-	UserOutput("Sending bootstrap-finished event.\n")
-	// client actually refers to localhost, so if we want to create
-	// an event, that has to be before the tear down starts.
-	// TODO: this should move to bootkube.sh
-	if _, err := client.CoreV1().Events("kube-system").Create(context.Background(), makeBootstrapSuccessEvent("kube-system", "bootstrap-finished"), metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
-		return err
-	}
-
-	// tear down the bootstrap control plane late after asset creation. Set bcp to nil to avoid a second tear down in the defer func.
-	if !b.earlyTearDown {
-		err = bcp.Teardown(b.terminationTimeout)
-		bcp = nil
-		if err != nil {
-			UserOutput("Error tearing down temporary bootstrap control plane: %v\n", err)
-		}
-	}
-
-	return nil
+	return bootstrapper.Run()
 }
 
 // All start command printing to stdout should go through this fmt.Printf wrapper.
@@ -266,6 +277,18 @@ func UserOutput(format string, a ...interface{}) {
 	fmt.Printf(format, a...)
 }
 
+// reportAssetProgress prints the number of created manifests whenever it has changed, until stop is closed.
+func reportAssetProgress(tracker *create.Tracker, stop <-chan struct{}) {
+	last := -1
+	wait.Until(func() {
+		done, total := tracker.Progress()
+		if total > 0 && done != last {
+			UserOutput("%d/%d manifests created\n", done, total)
+			last = done
+		}
+	}, assetProgressInterval, stop)
+}
+
 func waitForEvent(ctx context.Context, client kubernetes.Interface, ns, name string) error {
 	return wait.PollImmediateUntil(time.Second, func() (done bool, err error) {
 		if _, err := client.CoreV1().Events(ns).Get(ctx, name, metav1.GetOptions{}); err != nil && apierrors.IsNotFound(err) {
@@ -278,8 +301,30 @@ func waitForEvent(ctx context.Context, client kubernetes.Interface, ns, name str
 	}, ctx.Done())
 }
 
-func makeBootstrapSuccessEvent(ns, name string) *corev1.Event {
-	currentTime := metav1.Time{Time: time.Now()}
+// NewEventSink returns the EventSink of the start command, which creates and gets the events
+// with client, timestamped with clk.
+func NewEventSink(client kubernetes.Interface, clk clock.PassiveClock) EventSink {
+	return &eventSink{client: client, clock: clk}
+}
+
+type eventSink struct {
+	client kubernetes.Interface
+	clock  clock.PassiveClock
+}
+
+func (s *eventSink) SendEvent(ctx context.Context, ns, name string) error {
+	if _, err := s.client.CoreV1().Events(ns).Create(ctx, makeBootstrapSuccessEvent(ns, name, s.clock.Now()), metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *eventSink) WaitForEvent(ctx context.Context, ns, name string) error {
+	return waitForEvent(ctx, s.client, ns, name)
+}
+
+func makeBootstrapSuccessEvent(ns, name string, now time.Time) *corev1.Event {
+	currentTime := metav1.Time{Time: now}
 	event := &corev1.Event{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      name,
@@ -298,11 +343,16 @@ func makeBootstrapSuccessEvent(ns, name string) *corev1.Event {
 
 // isHAControlPlane HA is currently defined as 3 full control plane for this calculation.
 // TODO: Revaluate later if we need to re-address for HighlyAvailableArbiter.
-func isHAControlPlane(assetDir string) (bool, error) {
-	installConfig, err := getInstallConfig(filepath.Join(assetDir, assetPathClusterConfig))
+func isHAControlPlane(controlPlaneReplicas int) bool {
+	return controlPlaneReplicas >= 3
+}
+
+// controlPlaneReplicas returns the number of control plane replicas from the install config.
+func controlPlaneReplicas(assetDir string) (int, error) {
+	installConfig, err := installconfig.Load(filepath.Join(assetDir, assetPathClusterConfig))
 	if err != nil {
-		return false, fmt.Errorf("failed to get install config from cluster configmap: %w", err)
+		return 0, fmt.Errorf("failed to get install config from cluster configmap: %w", err)
 	}
 
-	return *installConfig.ControlPlane.Replicas >= 3, nil
+	return int(*installConfig.ControlPlane.Replicas), nil
 }
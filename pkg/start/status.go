@@ -25,13 +25,28 @@ func waitUntilPodsRunning(ctx context.Context, c kubernetes.Interface, pods map[
 	sc.Run()
 
 	if err := wait.PollImmediateUntil(5*time.Second, sc.AllRunningAndReady, ctx.Done()); err != nil {
-		return fmt.Errorf("error while checking pod status: %v", err)
+		return fmt.Errorf("error while checking pod status: %w", err)
 	}
 
 	UserOutput("All self-hosted control plane components successfully started\n")
 	return nil
 }
 
+// NewPodReadinessChecker returns the PodReadinessChecker of the start command, which watches
+// the pods matching the pod prefixes, keyed by their description.
+func NewPodReadinessChecker(client kubernetes.Interface, podPrefixes map[string][]string) PodReadinessChecker {
+	return &podReadinessChecker{client: client, podPrefixes: podPrefixes}
+}
+
+type podReadinessChecker struct {
+	client      kubernetes.Interface
+	podPrefixes map[string][]string
+}
+
+func (c *podReadinessChecker) WaitUntilPodsRunning(ctx context.Context) error {
+	return waitUntilPodsRunning(ctx, c.client, c.podPrefixes)
+}
+
 type statusController struct {
 	client           kubernetes.Interface
 	podStore         cache.Store
@@ -84,33 +99,56 @@ func (s *statusController) AllRunningAndReady() (bool, error) {
 	runningAndReady := true
 	for p, s := range ps {
 		if changed {
-			var status string
-			switch {
-			case s == nil:
-				status = "DoesNotExist"
-			case s.Phase == v1.PodRunning && s.IsReady:
-				status = "Ready"
-			case s.Phase == v1.PodRunning && !s.IsReady:
-				status = "RunningNotReady"
-			default:
-				status = string(s.Phase)
-			}
-
-			UserOutput("\tPod Status:%24s\t%s\n", p, status)
+			UserOutput("\tPod Status:%24s\t%s\n", p, s)
 		}
-		if s == nil || s.Phase != v1.PodRunning || !s.IsReady {
+		if !s.runningAndReady() {
 			runningAndReady = false
 		}
 	}
 	return runningAndReady, nil
 }
 
+// List fills the pod store with the current pods once. It is the one-shot alternative to Run.
+func (s *statusController) List(ctx context.Context) error {
+	pods, err := s.client.CoreV1().Pods("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	for i := range pods.Items {
+		if err := podStore.Add(&pods.Items[i]); err != nil {
+			return err
+		}
+	}
+	s.podStore = podStore
+	return nil
+}
+
 // podStatus describes a pod's phase and readiness.
 type podStatus struct {
 	Phase   v1.PodPhase
 	IsReady bool
 }
 
+func (s *podStatus) runningAndReady() bool {
+	return s != nil && s.Phase == v1.PodRunning && s.IsReady
+}
+
+// String returns the status as printed while waiting for the pods. A nil status is a pod
+// which does not exist.
+func (s *podStatus) String() string {
+	switch {
+	case s == nil:
+		return "DoesNotExist"
+	case s.Phase == v1.PodRunning && s.IsReady:
+		return "Ready"
+	case s.Phase == v1.PodRunning && !s.IsReady:
+		return "RunningNotReady"
+	default:
+		return string(s.Phase)
+	}
+}
+
 // podStatus retrieves the pod status by reading the PodPhase and whether it is ready.
 // A non existing pod is represented with nil.
 func (s *statusController) podStatus() (map[string]*podStatus, error) {
@@ -0,0 +1,70 @@
+package start
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	operatorversionedclient "github.com/openshift/client-go/operator/clientset/versioned"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// WaitForPods waits until the required pods are running and ready, like the start command
+// does before it waits for the self-hosted control plane, or until ctx is done.
+func WaitForPods(ctx context.Context, restConfig *rest.Config, podPrefixes map[string][]string) error {
+	client, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return err
+	}
+	return waitUntilPodsRunning(ctx, client, podPrefixes)
+}
+
+// WaitForControlPlane waits up to timeout until the self-hosted control plane is available,
+// like the start command does before it sends the bootstrap-success event. This is only
+// checked for HA control planes, i.e. of at least three replicas. If controlPlaneReplicas is
+// zero, it is read from the install config of the cluster.
+func WaitForControlPlane(ctx context.Context, restConfig *rest.Config, controlPlaneReplicas int, timeout time.Duration) error {
+	if controlPlaneReplicas == 0 {
+		client, err := kubernetes.NewForConfig(restConfig)
+		if err != nil {
+			return err
+		}
+		if controlPlaneReplicas, err = clusterControlPlaneReplicas(ctx, client); err != nil {
+			return err
+		}
+	}
+	if !isHAControlPlane(controlPlaneReplicas) {
+		UserOutput("Control plane of %d replicas is not highly available, not waiting for it\n", controlPlaneReplicas)
+		return nil
+	}
+
+	operatorClient, err := operatorversionedclient.NewForConfig(restConfig)
+	if err != nil {
+		return fmt.Errorf("error creating operator client config: %w", err)
+	}
+	gate := NewControlPlaneAvailabilityGate(operatorClient, controlPlaneReplicas, timeout)
+	UserOutput("Waiting for %s\n", gate.Name())
+	return gate.Wait(ctx)
+}
+
+// WaitForEvent waits until the event ns/name exists, or until ctx is done.
+func WaitForEvent(ctx context.Context, restConfig *rest.Config, ns, name string) error {
+	client, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return err
+	}
+	if err := waitForEvent(ctx, client, ns, name); err != nil {
+		return fmt.Errorf("error waiting for %s/%s event: %w", ns, name, err)
+	}
+	UserOutput("Got %s/%s event.\n", ns, name)
+	return nil
+}
+
+// IsTimeout returns true if the error of one of the wait functions is caused by the condition
+// not being satisfied in time.
+func IsTimeout(err error) bool {
+	return wait.Interrupted(err)
+}
@@ -1,3 +1,86 @@
 package version
 
+import (
+	"runtime"
+	"runtime/debug"
+)
+
 var Version string = "none"
+
+var (
+	// These are set through ldflags, like by the version-ldflags of build-machinery-go.
+	versionFromGit string
+	commitFromGit  string
+	gitTreeState   string
+	buildDate      string
+)
+
+// keyDependencies are the modules whose versions decide how assets are created and configs are
+// rendered.
+var keyDependencies = []string{
+	"github.com/openshift/library-go",
+	"k8s.io/client-go",
+	"github.com/openshift/installer",
+	"github.com/coreos/butane",
+}
+
+// Info is the build provenance of the binary.
+type Info struct {
+	Version      string       `json:"version"`
+	GitCommit    string       `json:"gitCommit,omitempty"`
+	GitTreeState string       `json:"gitTreeState,omitempty"`
+	BuildDate    string       `json:"buildDate,omitempty"`
+	GoVersion    string       `json:"goVersion"`
+	Platform     string       `json:"platform"`
+	Dependencies []Dependency `json:"dependencies,omitempty"`
+}
+
+// Dependency is the version of a module built into the binary.
+type Dependency struct {
+	Path    string `json:"path"`
+	Version string `json:"version"`
+	// Replace is the module path and version replacing the dependency, if any.
+	Replace string `json:"replace,omitempty"`
+}
+
+// Get returns the build provenance of the binary. The versions of the key dependencies are
+// only known if the binary was built with module support.
+func Get() Info {
+	info := Info{
+		Version:      Version,
+		GitCommit:    commitFromGit,
+		GitTreeState: gitTreeState,
+		BuildDate:    buildDate,
+		GoVersion:    runtime.Version(),
+		Platform:     runtime.GOOS + "/" + runtime.GOARCH,
+	}
+	if info.Version == "none" && len(versionFromGit) > 0 {
+		info.Version = versionFromGit
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		info.Dependencies = dependencies(bi)
+	}
+	return info
+}
+
+// dependencies returns the key dependencies found in the build info, in the order of
+// keyDependencies.
+func dependencies(bi *debug.BuildInfo) []Dependency {
+	modules := map[string]*debug.Module{}
+	for _, m := range bi.Deps {
+		modules[m.Path] = m
+	}
+	var deps []Dependency
+	for _, path := range keyDependencies {
+		m, ok := modules[path]
+		if !ok {
+			continue
+		}
+		dep := Dependency{Path: m.Path, Version: m.Version}
+		if m.Replace != nil {
+			dep.Replace = m.Replace.Path + "@" + m.Replace.Version
+		}
+		deps = append(deps, dep)
+	}
+	return deps
+}
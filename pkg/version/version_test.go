@@ -0,0 +1,26 @@
+package version
+
+import (
+	"reflect"
+	"runtime/debug"
+	"testing"
+)
+
+func TestDependencies(t *testing.T) {
+	bi := &debug.BuildInfo{
+		Deps: []*debug.Module{
+			{Path: "github.com/coreos/butane", Version: "v0.17.0"},
+			{Path: "github.com/spf13/cobra", Version: "v1.7.0"},
+			{Path: "k8s.io/client-go", Version: "v0.27.4"},
+			{Path: "github.com/openshift/installer", Version: "v0.16.1", Replace: &debug.Module{Path: "github.com/openshift/installer", Version: "v0.0.0-20230801000000-abcdef012345"}},
+		},
+	}
+	expected := []Dependency{
+		{Path: "k8s.io/client-go", Version: "v0.27.4"},
+		{Path: "github.com/openshift/installer", Version: "v0.16.1", Replace: "github.com/openshift/installer@v0.0.0-20230801000000-abcdef012345"},
+		{Path: "github.com/coreos/butane", Version: "v0.17.0"},
+	}
+	if got := dependencies(bi); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+}